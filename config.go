@@ -0,0 +1,257 @@
+package main
+
+import (
+	"bytes"
+	"errors"
+	"flag"
+	"io"
+	"os"
+	"time"
+
+	"gopkg.in/yaml.v3"
+
+	"mdelclaro/web-crawler/crawler"
+)
+
+// Config holds every option of a crawl. The flags and the -config file both
+// fill it in, the flags taking precedence. Keys in the file are named after
+// the flags, e.g. "max-pages: 100", and lists like include are YAML
+// sequences.
+type Config struct {
+	// File is the -config file, which can't name another one.
+	File string `yaml:"-"`
+
+	Target            string        `yaml:"url"`
+	Dir               string        `yaml:"dir"`
+	S3                string        `yaml:"s3"`
+	S3Endpoint        string        `yaml:"s3-endpoint"`
+	Depth             int           `yaml:"depth"`
+	MaxIdleConns      int           `yaml:"max-idle-conns"`
+	MaxConnsPerHost   int           `yaml:"max-conns-per-host"`
+	IdleTimeout       time.Duration `yaml:"idle-conn-timeout"`
+	TLSTimeout        time.Duration `yaml:"tls-handshake-timeout"`
+	HTTP2             bool          `yaml:"http2"`
+	Timeout           time.Duration `yaml:"timeout"`
+	MaxSize           int64         `yaml:"max-size"`
+	RateLimit         string        `yaml:"rate-limit"`
+	PerHostRate       string        `yaml:"per-host-rate"`
+	Concurrency       int           `yaml:"concurrency"`
+	Sequential        bool          `yaml:"sequential"`
+	MaxFDs            int           `yaml:"max-file-descriptors"`
+	Strategy          string        `yaml:"strategy"`
+	Scope             string        `yaml:"scope"`
+	Layout            string        `yaml:"layout"`
+	Ext               string        `yaml:"ext"`
+	IgnoreRobots      bool          `yaml:"ignore-robots"`
+	IgnoreMetaRobots  bool          `yaml:"ignore-meta-robots"`
+	RobotsTTL         time.Duration `yaml:"robots-ttl"`
+	StrictRobots      bool          `yaml:"strict-robots"`
+	Delay             time.Duration `yaml:"delay"`
+	Jitter            float64       `yaml:"jitter"`
+	Report            string        `yaml:"report"`
+	Diff              string        `yaml:"diff"`
+	DiffJSON          string        `yaml:"diff-json"`
+	Sitemap           string        `yaml:"sitemap"`
+	Graph             string        `yaml:"graph"`
+	BrokenLinks       string        `yaml:"broken-links"`
+	FromSitemap       bool          `yaml:"from-sitemap"`
+	StatsJSON         string        `yaml:"stats-json"`
+	MaxPages          int           `yaml:"max-pages"`
+	MaxDuration       time.Duration `yaml:"max-duration"`
+	UserAgent         string        `yaml:"user-agent"`
+	MaxRedirects      int           `yaml:"max-redirects"`
+	MaxPathFetches    int           `yaml:"max-path-fetches"`
+	MaxLinksPerPage   int           `yaml:"max-links-per-page"`
+	CheckExternal     bool          `yaml:"check-external"`
+	Retries           int           `yaml:"retries"`
+	RetryStatus       string        `yaml:"retry-status"`
+	MaxHostFailures   int           `yaml:"max-host-failures"`
+	HostCooldown      time.Duration `yaml:"host-cooldown"`
+	IncludeSubdomains bool          `yaml:"include-subdomains"`
+	FollowPagination  bool          `yaml:"follow-pagination"`
+	Assets            bool          `yaml:"assets"`
+	AssetsCrossOrigin bool          `yaml:"assets-cross-origin"`
+	Mirror            bool          `yaml:"mirror"`
+	StripFragments    bool          `yaml:"strip-fragments"`
+	BasePath          string        `yaml:"base-path"`
+	Include           stringList    `yaml:"include"`
+	Exclude           stringList    `yaml:"exclude"`
+	ExcludeFile       string        `yaml:"exclude-file"`
+	Accept            stringList    `yaml:"accept"`
+	KeepQuery         bool          `yaml:"keep-query"`
+	IndexName         string        `yaml:"index-name"`
+	LowercasePaths    bool          `yaml:"lowercase-paths"`
+	CaseInsensitive   bool          `yaml:"case-insensitive-paths"`
+	Resume            bool          `yaml:"resume"`
+	ResumeFrom        string        `yaml:"resume-from"`
+	ResumePartial     bool          `yaml:"resume-partial"`
+	Refresh           bool          `yaml:"refresh"`
+	StateFile         string        `yaml:"state-file"`
+	WARC              string        `yaml:"warc"`
+	DedupContent      bool          `yaml:"dedup-content"`
+	SaveRawHeaders    bool          `yaml:"save-raw-headers"`
+	NoTranscode       bool          `yaml:"no-transcode"`
+	DryRun            bool          `yaml:"dry-run"`
+	HeadFirst         bool          `yaml:"head-first"`
+	DetectSoft404     bool          `yaml:"detect-soft-404"`
+	HonorCanonical    bool          `yaml:"honor-canonical"`
+	Progress          bool          `yaml:"progress"`
+	MetricsAddr       string        `yaml:"metrics-addr"`
+	Render            string        `yaml:"render"`
+	BasicAuth         string        `yaml:"basic-auth"`
+	Headers           stringList    `yaml:"header"`
+	Cookies           stringList    `yaml:"cookie"`
+	CookieFile        string        `yaml:"cookie-file"`
+	Proxy             string        `yaml:"proxy"`
+	Proxies           string        `yaml:"proxies"`
+	Insecure          bool          `yaml:"insecure"`
+	UpgradeInsecure   bool          `yaml:"upgrade-insecure"`
+	Seeds             string        `yaml:"seeds"`
+	AllowedDomains    string        `yaml:"allowed-domains"`
+	Verbose           bool          `yaml:"verbose"`
+	Quiet             bool          `yaml:"quiet"`
+	OnlyErrors        bool          `yaml:"only-errors"`
+}
+
+// defaultConfig returns the options of a crawl given no flags.
+func defaultConfig() Config {
+	return Config{
+		MaxIdleConns:   10,
+		IdleTimeout:    90 * time.Second,
+		TLSTimeout:     10 * time.Second,
+		HTTP2:          true,
+		Timeout:        10 * time.Second,
+		MaxSize:        crawler.DefaultMaxSize,
+		Concurrency:    10,
+		Layout:         crawler.LayoutMirror,
+		Scope:          crawler.ScopePath,
+		Strategy:       crawler.StrategyDFS,
+		UserAgent:      crawler.DefaultUserAgent,
+		MaxRedirects:   10,
+		MaxPathFetches: crawler.DefaultMaxPathFetches,
+		Retries:        2,
+		HostCooldown:   30 * time.Second,
+		Ext:            crawler.ExtHTML,
+		IndexName:      "index.html",
+	}
+}
+
+// flagSet returns the flags setting the options of c. The current values
+// of c are the defaults, so flags parsed after loading a file only override
+// the values they are given. Repeatable flags add to the lists of the file.
+func (c *Config) flagSet() *flag.FlagSet {
+	fs := flag.NewFlagSet(os.Args[0], flag.ExitOnError)
+	fs.StringVar(&c.File, "config", c.File, "YAML or JSON file setting any of these options, keyed by flag name; flags take precedence")
+	fs.StringVar(&c.Target, "url", c.Target, "target URL, or several separated by commas")
+	fs.StringVar(&c.Dir, "dir", c.Dir, "directory where files will be saved")
+	fs.StringVar(&c.S3, "s3", c.S3, "save files to this S3 bucket, as bucket or bucket/prefix, under their path in dir; credentials come from AWS_ACCESS_KEY_ID, AWS_SECRET_ACCESS_KEY and AWS_SESSION_TOKEN, the region from AWS_REGION")
+	fs.StringVar(&c.S3Endpoint, "s3-endpoint", c.S3Endpoint, "base URL of an S3-compatible service to use with -s3 instead of AWS")
+	fs.IntVar(&c.Depth, "depth", c.Depth, "max link-hops away from the target (0 means unlimited)")
+	fs.IntVar(&c.MaxIdleConns, "max-idle-conns", c.MaxIdleConns, "max idle keep-alive connections per host")
+	fs.IntVar(&c.MaxConnsPerHost, "max-conns-per-host", c.MaxConnsPerHost, "max connections open to a single host (0 means only -concurrency limits them)")
+	fs.DurationVar(&c.IdleTimeout, "idle-conn-timeout", c.IdleTimeout, "close keep-alive connections idle for this long (0 means never)")
+	fs.DurationVar(&c.TLSTimeout, "tls-handshake-timeout", c.TLSTimeout, "max time for the TLS handshake of a new connection (0 means no timeout)")
+	fs.BoolVar(&c.HTTP2, "http2", c.HTTP2, "use HTTP/2 with servers offering it, -http2=false forces HTTP/1.1")
+	fs.DurationVar(&c.Timeout, "timeout", c.Timeout, "per-request timeout (0 means no timeout)")
+	fs.StringVar(&c.RateLimit, "rate-limit", c.RateLimit, "max download bandwidth for the whole crawl, like 500KB/s or 2MiB/s")
+	fs.StringVar(&c.PerHostRate, "per-host-rate", c.PerHostRate, "max download bandwidth from each host, under -rate-limit for the whole crawl")
+	fs.Int64Var(&c.MaxSize, "max-size", c.MaxSize, "max bytes read from a single response (0 means unlimited)")
+	fs.IntVar(&c.Concurrency, "concurrency", c.Concurrency, "max pages downloaded in parallel; higher is faster but uses more sockets and memory")
+	fs.BoolVar(&c.Sequential, "sequential", c.Sequential, "crawl on a single worker in document order, requesting the same urls in the same order on every run")
+	fs.IntVar(&c.MaxFDs, "max-file-descriptors", c.MaxFDs, "file descriptors the crawl may use for connections and output files; -concurrency is lowered, with a warning, when it needs more (0 means the open file limit of the process on Unix, -1 means no limit)")
+	fs.StringVar(&c.Layout, "layout", c.Layout, "how files are arranged in dir: mirror follows the url path, flat names them by hash in dir itself, hostname puts every host in its own directory")
+	fs.StringVar(&c.Scope, "scope", c.Scope, "links followed: path keeps children of the target, host the whole host, domain subdomains too")
+	fs.StringVar(&c.Strategy, "strategy", c.Strategy, "crawl order: bfs visits shallow pages first, dfs follows links deep first")
+	fs.BoolVar(&c.IgnoreRobots, "ignore-robots", c.IgnoreRobots, "do not fetch or honor robots.txt")
+	fs.DurationVar(&c.RobotsTTL, "robots-ttl", c.RobotsTTL, "fetch a host's robots.txt again once it is this old (0 keeps it for the whole crawl)")
+	fs.BoolVar(&c.StrictRobots, "strict-robots", c.StrictRobots, "disallow hosts whose robots.txt is answered with a 5xx, instead of allowing them")
+	fs.BoolVar(&c.IgnoreMetaRobots, "ignore-meta-robots", c.IgnoreMetaRobots, "ignore robots <meta> tags and rel=\"nofollow\" links")
+	fs.DurationVar(&c.Delay, "delay", c.Delay, "minimum interval between requests to the same host")
+	fs.Float64Var(&c.Jitter, "jitter", c.Jitter, "randomize each -delay by up to this fraction of it, e.g. 0.5 for ±50%")
+	fs.StringVar(&c.Report, "report", c.Report, "file where a JSON report of the crawl is written")
+	fs.StringVar(&c.Diff, "diff", c.Diff, "-report of a previous crawl to compare this one to, printing the added, removed and changed urls; it can be the file -report overwrites")
+	fs.StringVar(&c.DiffJSON, "diff-json", c.DiffJSON, "file where the -diff is written as JSON")
+	fs.StringVar(&c.StatsJSON, "stats-json", c.StatsJSON, "file where the crawl statistics are written as JSON")
+	fs.BoolVar(&c.FromSitemap, "from-sitemap", c.FromSitemap, "also crawl the pages listed in the target's /sitemap.xml")
+	fs.StringVar(&c.Graph, "graph", c.Graph, "file where the links between crawled pages are written as a GraphViz DOT graph")
+	fs.StringVar(&c.BrokenLinks, "broken-links", c.BrokenLinks, "file where the urls answered with a non-2xx status are listed with the pages linking to them")
+	fs.StringVar(&c.Sitemap, "sitemap", c.Sitemap, "file where a sitemap.xml of the crawled pages is written")
+	fs.IntVar(&c.MaxPages, "max-pages", c.MaxPages, "stop after downloading this many pages (0 means unlimited)")
+	fs.DurationVar(&c.MaxDuration, "max-duration", c.MaxDuration, "stop the crawl after this long (0 means unlimited)")
+	fs.StringVar(&c.UserAgent, "user-agent", c.UserAgent, "User-Agent header sent with every request")
+	fs.IntVar(&c.MaxRedirects, "max-redirects", c.MaxRedirects, "max redirects followed per request (0 disables following)")
+	fs.BoolVar(&c.CheckExternal, "check-external", c.CheckExternal, "check off-site links once with a HEAD request and report the broken ones, without crawling them")
+	fs.IntVar(&c.MaxLinksPerPage, "max-links-per-page", c.MaxLinksPerPage, "max links followed from a single page (0 means unlimited)")
+	fs.IntVar(&c.MaxPathFetches, "max-path-fetches", c.MaxPathFetches, "max downloads of urls sharing a host and path, whatever their query (0 means unlimited)")
+	fs.IntVar(&c.Retries, "retries", c.Retries, "retries after connection errors, 5xx and 429 responses")
+	fs.StringVar(&c.RetryStatus, "retry-status", c.RetryStatus, "comma-separated statuses to retry instead of 5xx and 429, e.g. 429,503,403")
+	fs.IntVar(&c.MaxHostFailures, "max-host-failures", c.MaxHostFailures, "stop requesting a host for -host-cooldown after this many requests to it failed in a row without a response (0 means never)")
+	fs.DurationVar(&c.HostCooldown, "host-cooldown", c.HostCooldown, "how long a host tripped by -max-host-failures is skipped before a single request probes it again")
+	fs.BoolVar(&c.IncludeSubdomains, "include-subdomains", c.IncludeSubdomains, "also crawl subdomains of the target's domain")
+	fs.BoolVar(&c.FollowPagination, "follow-pagination", c.FollowPagination, "follow rel=\"next\" links even out of the path scope")
+	fs.BoolVar(&c.Assets, "assets", c.Assets, "also download images, stylesheets and scripts")
+	fs.BoolVar(&c.AssetsCrossOrigin, "assets-cross-origin", c.AssetsCrossOrigin, "download assets hosted on other domains too")
+	fs.BoolVar(&c.Mirror, "mirror", c.Mirror, "rewrite links in saved pages to the local copies for offline browsing")
+	fs.BoolVar(&c.StripFragments, "strip-fragments", c.StripFragments, "drop the #fragment of links rewritten by -mirror")
+	fs.StringVar(&c.BasePath, "base-path", c.BasePath, "url path the mirror is served from, e.g. /archive/, making -mirror links root-relative under it")
+	fs.Var(&c.Include, "include", "only follow urls matching this regexp (repeatable)")
+	fs.Var(&c.Exclude, "exclude", "never follow urls matching this regexp (repeatable)")
+	fs.StringVar(&c.ExcludeFile, "exclude-file", c.ExcludeFile, "file of path globs or prefixes, one per line, never followed, like \"/drafts\" or \"*.pdf\"")
+	fs.Var(&c.Accept, "accept", "only keep pages of this content type, like text/html or image/* (repeatable)")
+	fs.BoolVar(&c.KeepQuery, "keep-query", c.KeepQuery, "treat urls with different query strings as distinct pages")
+	fs.StringVar(&c.Ext, "ext", c.Ext, "extension of saved pages: html appends .html, auto uses the one of the Content-Type, preserve keeps the url's")
+	fs.StringVar(&c.IndexName, "index-name", c.IndexName, "file name of pages whose url ends in \"/\"")
+	fs.BoolVar(&c.LowercasePaths, "lowercase-paths", c.LowercasePaths, "treat urls whose paths differ only in case as the same page")
+	fs.BoolVar(&c.CaseInsensitive, "case-insensitive-paths", c.CaseInsensitive, "ignore the case of paths in the path scope and in telling pages apart, but request urls with the case they were linked with")
+	fs.BoolVar(&c.Refresh, "refresh", c.Refresh, "revalidate every saved page with the server, even those still fresh")
+	fs.BoolVar(&c.Resume, "resume", c.Resume, "continue an interrupted crawl from its state file")
+	fs.StringVar(&c.ResumeFrom, "resume-from", c.ResumeFrom, "-report of a previous crawl whose failed urls are crawled again, skipping the ones it got")
+	fs.BoolVar(&c.ResumePartial, "resume-partial", c.ResumePartial, "keep assets interrupted mid-download as .part files and continue them with range requests")
+	fs.BoolVar(&c.DedupContent, "dedup-content", c.DedupContent, "store identical bodies once, under .content in dir, and hard link the pages serving them")
+	fs.BoolVar(&c.SaveRawHeaders, "save-raw-headers", c.SaveRawHeaders, "write the status line and headers of every page to a .headers file next to it")
+	fs.StringVar(&c.WARC, "warc", c.WARC, "also archive every download as gzip-compressed WARC records to this file, e.g. out.warc.gz")
+	fs.StringVar(&c.StateFile, "state-file", c.StateFile, "file where the crawl state is saved (defaults to .crawl-state.json in dir)")
+	fs.BoolVar(&c.NoTranscode, "no-transcode", c.NoTranscode, "save pages in their original charset instead of converting them to utf-8")
+	fs.BoolVar(&c.DryRun, "dry-run", c.DryRun, "list the urls that would be crawled and where they'd be saved, without saving anything")
+	fs.StringVar(&c.Render, "render", c.Render, "command printing a page's DOM after its scripts ran, given the url, to find links added by JavaScript, e.g. \"chromium --headless --dump-dom\"")
+	fs.BoolVar(&c.Progress, "progress", c.Progress, "write a progress line to stderr every few seconds")
+	fs.StringVar(&c.MetricsAddr, "metrics-addr", c.MetricsAddr, "address serving Prometheus metrics of the crawl on /metrics, e.g. :9100")
+	fs.BoolVar(&c.HonorCanonical, "honor-canonical", c.HonorCanonical, "dedup and name pages by their same-origin canonical link instead of the fetched url")
+	fs.BoolVar(&c.HeadFirst, "head-first", c.HeadFirst, "send a HEAD request before downloading a page and skip the ones that wouldn't be kept")
+	fs.BoolVar(&c.DetectSoft404, "detect-soft-404", c.DetectSoft404, "skip pages matching the page a host answers with a 200 for a random path")
+	fs.StringVar(&c.BasicAuth, "basic-auth", c.BasicAuth, "user:pass sent as HTTP basic auth with every request")
+	fs.Var(&c.Headers, "header", "\"Key: Value\" header sent with every request (repeatable)")
+	fs.Var(&c.Cookies, "cookie", "\"name=value\" cookie sent to the target (repeatable)")
+	fs.StringVar(&c.CookieFile, "cookie-file", c.CookieFile, "Netscape cookies.txt file to load cookies from")
+	fs.StringVar(&c.Proxy, "proxy", c.Proxy, "http://, https:// or socks5:// proxy url (defaults to HTTP_PROXY/HTTPS_PROXY)")
+	fs.StringVar(&c.Proxies, "proxies", c.Proxies, "file with proxy urls, one per line, used in turn for each request")
+	fs.BoolVar(&c.Insecure, "insecure", c.Insecure, "skip TLS certificate verification (only for trusted internal sites)")
+	fs.BoolVar(&c.UpgradeInsecure, "upgrade-insecure", c.UpgradeInsecure, "fetch http links to the same host over https, falling back to http for hosts where https fails")
+	fs.StringVar(&c.Seeds, "seeds", c.Seeds, "file with more urls to start from, one per line")
+	fs.StringVar(&c.AllowedDomains, "allowed-domains", c.AllowedDomains, "comma-separated domains whose hosts may all be crawled, instead of each seed's own host")
+	fs.StringVar(&c.AllowedDomains, "domains", c.AllowedDomains, "shorthand for -allowed-domains")
+	fs.BoolVar(&c.Verbose, "verbose", c.Verbose, "also log every download and extraction step")
+	fs.BoolVar(&c.Quiet, "quiet", c.Quiet, "only log failures")
+	fs.BoolVar(&c.OnlyErrors, "only-errors", c.OnlyErrors, "only log failures, skip the statistics and exit with status 1 if any page failed")
+
+	return fs
+}
+
+// load reads the options set in the YAML or JSON file fileName into c.
+// Options the file doesn't mention keep their value, and unknown ones are an
+// error.
+func (c *Config) load(fileName string) error {
+	data, err := os.ReadFile(fileName)
+	if err != nil {
+		return err
+	}
+
+	dec := yaml.NewDecoder(bytes.NewReader(data))
+	dec.KnownFields(true)
+	// an empty file sets nothing
+	if err := dec.Decode(c); err != nil && !errors.Is(err, io.EOF) {
+		return err
+	}
+
+	return nil
+}
@@ -0,0 +1,275 @@
+package crawler
+
+import (
+	"context"
+	"net/url"
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+
+	"golang.org/x/net/html"
+)
+
+// assetAttrs maps the elements whose resources a page needs to render to the
+// attribute holding the resource url.
+var assetAttrs = map[string]string{
+	"img":    "src",
+	"script": "src",
+	"link":   "href",
+}
+
+// assetRels are the <link rel> values that point at a resource rather than
+// at another page.
+var assetRels = map[string]bool{
+	"stylesheet":       true,
+	"icon":             true,
+	"apple-touch-icon": true,
+	"mask-icon":        true,
+	"manifest":         true,
+	"preload":          true,
+}
+
+// extractAssets returns the absolute urls of the images, scripts and
+// stylesheets referenced by a page, and of the resources its inline styles
+// load.
+func (c *Crawler) extractAssets(htmlDoc *html.Node, pageURL *url.URL) []string {
+	assets := []string{}
+	found := map[string]struct{}{}
+	add := func(u string) {
+		if _, ok := found[u]; !ok {
+			found[u] = struct{}{}
+			assets = append(assets, u)
+		}
+	}
+
+	baseURL := findBase(htmlDoc, pageURL)
+
+	var f func(*html.Node)
+	f = func(n *html.Node) {
+		if n.Type == html.ElementNode {
+			if attr, ok := assetAttrs[n.Data]; ok && (n.Data != "link" || isAssetLink(n)) {
+				for _, a := range n.Attr {
+					if a.Key != attr || a.Val == "" {
+						continue
+					}
+
+					if u, ok := c.assetURL(a.Val, baseURL, pageURL); ok {
+						add(u)
+					}
+				}
+			}
+
+			for _, a := range n.Attr {
+				if a.Key == "style" {
+					for _, u := range c.extractCSS(a.Val, baseURL, pageURL) {
+						add(u)
+					}
+				}
+			}
+
+			if n.Data == "style" {
+				for child := n.FirstChild; child != nil; child = child.NextSibling {
+					if child.Type == html.TextNode {
+						for _, u := range c.extractCSS(child.Data, baseURL, pageURL) {
+							add(u)
+						}
+					}
+				}
+			}
+		}
+		for child := n.FirstChild; child != nil; child = child.NextSibling {
+			f(child)
+		}
+	}
+	f(htmlDoc)
+
+	return assets
+}
+
+// assetURL resolves the reference ref against base and reports whether the
+// resource it points at should be downloaded along with the page at
+// pageURL.
+func (c *Crawler) assetURL(ref string, base, pageURL *url.URL) (string, bool) {
+	refURL, err := url.Parse(strings.TrimSpace(ref))
+	if err != nil {
+		return "", false
+	}
+
+	resolved := base.ResolveReference(refURL)
+	resolved.Fragment = ""
+
+	// skip data: and other inline sources
+	if resolved.Scheme != "http" && resolved.Scheme != "https" {
+		return "", false
+	}
+
+	if !c.AssetsCrossOrigin && !c.sameSite(resolved, pageURL) {
+		return "", false
+	}
+
+	return resolved.String(), true
+}
+
+// isAssetLink reports whether a <link> element loads a resource.
+func isAssetLink(n *html.Node) bool {
+	for _, a := range n.Attr {
+		if a.Key == "rel" {
+			for _, rel := range strings.Fields(strings.ToLower(a.Val)) {
+				if assetRels[rel] {
+					return true
+				}
+			}
+		}
+	}
+
+	return false
+}
+
+// visitAsset downloads a single asset and saves it as is. Assets are never
+// parsed for links, but stylesheets are for the resources they load, which
+// are returned.
+func (c *Crawler) visitAsset(ctx context.Context, target string) (assets []string, err error) {
+	if ctx.Err() != nil {
+		return nil, nil
+	}
+
+	assetURL, err := url.Parse(target)
+	if err != nil {
+		return nil, &PageError{URL: target, Err: err}
+	}
+	assetURL.RawQuery = ""
+
+	raw := target
+	target = normalizeURL(assetURL, c.LowercasePaths).String()
+	key := normalizeURL(assetURL, c.LowercasePaths || c.CaseInsensitivePaths).String()
+	if _, seen := c.visited.LoadOrStore(key, struct{}{}); seen {
+		c.pending.Delete(raw)
+		return nil, nil
+	}
+
+	if !c.allowed(ctx, assetURL) {
+		c.Logger.Info("disallowed by robots.txt, skipping", "url", target)
+		c.pending.Delete(raw)
+		return nil, nil
+	}
+
+	fp, fileName := c.assetPath(assetURL)
+	rec := Record{URL: target, Path: filepath.Join(fp, fileName), Asset: true}
+	defer func() {
+		c.addRecord(rec)
+		if rec.Error == "" {
+			c.markDone(raw, rec, 0, nil, assets)
+		}
+	}()
+
+	// assets don't change often, keep the copy we have
+	if size, ok := c.savedSize(rec.Path); ok {
+		c.Logger.Info("already saved", "path", rec.Path)
+		rec.Cached = true
+		rec.ContentLength = int(size)
+		return c.stylesheetAssets(assetURL, rec), nil
+	}
+
+	if c.DryRun {
+		rec.ContentType, _ = c.probe(ctx, target)
+		c.logSaved(target, rec.Path)
+		return nil, nil
+	}
+
+	// assets can be big, they go straight to disk when they are saved
+	// there
+	var resp *response
+	if c.onDisk() {
+		resp, err = c.downloadTo(ctx, target, rec.Path)
+	} else if resp, err = c.download(ctx, target); err == nil {
+		resp.size = int64(len(resp.body))
+		resp.hash, err = c.saveBody(fp, fileName, resp.body, ObjectMeta{URL: target, ContentType: resp.contentType})
+	}
+	if resp != nil {
+		rec.StatusCode = resp.status
+		rec.ContentType = resp.contentType
+	}
+	if err != nil {
+		c.Logger.Error("error downloading the asset", "url", target, "err", err)
+		rec.Path = ""
+		rec.Error = err.Error()
+		if isFatal(err) {
+			return nil, err
+		}
+		return nil, &PageError{URL: target, Err: err}
+	}
+	rec.ContentLength = int(resp.size)
+	rec.ContentHash = resp.hash
+	c.logSaved(target, rec.Path)
+
+	return c.stylesheetAssets(assetURL, rec), nil
+}
+
+// savedSize returns the size of the file saved as name, reporting false
+// when there is none.
+func (c *Crawler) savedSize(name string) (int64, bool) {
+	if c.onDisk() {
+		info, err := os.Stat(name)
+		if err != nil {
+			return 0, false
+		}
+		return info.Size(), true
+	}
+
+	data, ok := c.store().Get(name)
+
+	return int64(len(data)), ok
+}
+
+// stylesheetAssets returns the resources loaded by the asset saved for rec
+// when it is a stylesheet. They are relative to the stylesheet, not to the
+// page using it.
+func (c *Crawler) stylesheetAssets(assetURL *url.URL, rec Record) []string {
+	if !isCSS(assetURL, rec.ContentType) {
+		return nil
+	}
+
+	data, ok := c.store().Get(rec.Path)
+	if !ok {
+		c.Logger.Error("error reading the stylesheet", "path", rec.Path)
+		return nil
+	}
+
+	c.Logger.Debug("extracting assets", "url", rec.URL)
+
+	return c.extractCSS(string(data), assetURL, assetURL)
+}
+
+// assetPath returns the directory and file name an asset is saved under.
+// Assets keep their own name; those from other hosts are grouped under a
+// directory named after the host. PathFor and LayoutFlat name them like
+// pages, with LayoutFlat keeping their extension.
+func (c *Crawler) assetPath(u *url.URL) (string, string) {
+	if c.PathFor != nil {
+		return c.customPath(u, false)
+	}
+	if c.Layout == LayoutFlat {
+		return c.dir, c.flatName(u) + sanitizeFileName(path.Ext(u.Path))
+	}
+
+	dir := c.hostDir(u)
+
+	segments := pathSegments(u.Path)
+	if len(segments) == 0 || strings.HasSuffix(u.Path, "/") {
+		segments = append(segments, "index")
+	}
+	last := len(segments) - 1
+
+	return filepath.Join(append([]string{dir}, segments[:last]...)...), segments[last]
+}
+
+// targetHost returns the host of the crawl target.
+func (c *Crawler) targetHost() string {
+	u, err := url.Parse(c.target)
+	if err != nil {
+		return ""
+	}
+
+	return u.Host
+}
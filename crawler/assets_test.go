@@ -0,0 +1,188 @@
+package crawler
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"path/filepath"
+	"reflect"
+	"sort"
+	"testing"
+)
+
+func TestCrawler_extractAssets(t *testing.T) {
+	page := `<html><head>
+<link rel="stylesheet" href="/static/site.css">
+<link rel="canonical" href="/docs">
+<link rel="shortcut icon" href="/favicon.ico">
+<script src="app.js"></script>
+<script>inline()</script>
+<style>/* url(/commented.png) */ body { background: url("/img/bg.png") }</style>
+</head><body>
+<img src="https://cdn.example.net/logo.png">
+<img src="data:image/png;base64,AAAA">
+<img src="/static/site.css">
+<div style="background-image: url(hero.jpg)"></div>
+</body></html>`
+
+	type args struct {
+		crossOrigin bool
+	}
+	tests := []struct {
+		name string
+		args args
+		want []string
+	}{
+		{
+			name: "Test same-site assets only",
+			args: args{crossOrigin: false},
+			want: []string{
+				"https://example.com/static/site.css",
+				"https://example.com/favicon.ico",
+				"https://example.com/docs/app.js",
+				"https://example.com/img/bg.png",
+				"https://example.com/docs/hero.jpg",
+			},
+		},
+		{
+			name: "Test cross-origin assets",
+			args: args{crossOrigin: true},
+			want: []string{
+				"https://example.com/static/site.css",
+				"https://example.com/favicon.ico",
+				"https://example.com/docs/app.js",
+				"https://example.com/img/bg.png",
+				"https://cdn.example.net/logo.png",
+				"https://example.com/docs/hero.jpg",
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			doc, err := parseHTML([]byte(page))
+			if err != nil {
+				t.Fatalf("parseHTML() error = %v", err)
+			}
+
+			pageURL, _ := url.Parse("https://example.com/docs/")
+			c := New(pageURL.String(), t.TempDir())
+			c.AssetsCrossOrigin = tt.args.crossOrigin
+
+			if got := c.extractAssets(doc, pageURL); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("extractAssets() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestCrawler_extractCSS(t *testing.T) {
+	tests := []struct {
+		name string
+		css  string
+		want []string
+	}{
+		{
+			name: "Test quoted and bare urls",
+			css:  `a { background: url('a.png') } b { background: url("b.png") } c { background: url( c.png ) }`,
+			want: []string{
+				"https://example.com/static/css/a.png",
+				"https://example.com/static/css/b.png",
+				"https://example.com/static/css/c.png",
+			},
+		},
+		{
+			name: "Test imports",
+			css:  `@import "base.css"; @import url(../print.css) print;`,
+			want: []string{
+				"https://example.com/static/css/base.css",
+				"https://example.com/static/print.css",
+			},
+		},
+		{
+			name: "Test font urls keep their path without the fragment",
+			css:  `@font-face { src: url("/fonts/a.woff2") format("woff2"), url(/fonts/a.svg#font) }`,
+			want: []string{
+				"https://example.com/fonts/a.woff2",
+				"https://example.com/fonts/a.svg",
+			},
+		},
+		{
+			name: "Test data urls, comments and other sites are skipped",
+			css:  `/* url(old.png) */ a { background: url(data:image/png;base64,AAAA) } b { background: url(https://cdn.example.net/x.png) }`,
+			want: []string{},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			base, _ := url.Parse("https://example.com/static/css/site.css")
+			c := New("https://example.com", t.TempDir())
+
+			if got := c.extractCSS(tt.css, base, base); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("extractCSS() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestCrawler_RunStylesheets(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/docs":
+			fmt.Fprint(w, `<html><head><link rel="stylesheet" href="/static/css/site.css"></head></html>`)
+		case "/static/css/site.css":
+			w.Header().Set("Content-Type", "text/css")
+			fmt.Fprint(w, `@import "fonts.css"; body { background: url(../img/bg.png) }`)
+		case "/static/css/fonts.css":
+			w.Header().Set("Content-Type", "text/css")
+			fmt.Fprint(w, `@font-face { src: url(/fonts/a.woff2) }`)
+		case "/static/img/bg.png", "/fonts/a.woff2":
+			fmt.Fprint(w, "binary")
+		default:
+			http.NotFound(w, r)
+		}
+	}))
+	defer server.Close()
+
+	tests := []struct {
+		name string
+		want []string
+	}{
+		{
+			name: "Test resources of stylesheets are downloaded",
+			want: []string{
+				filepath.Join("fonts", "a.woff2"),
+				filepath.Join("static", "css", "fonts.css"),
+				filepath.Join("static", "css", "site.css"),
+				filepath.Join("static", "img", "bg.png"),
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			dir := t.TempDir()
+			c := New(server.URL+"/docs", dir)
+			c.IgnoreRobots = true
+			c.Assets = true
+			if err := c.Run(context.Background()); err != nil {
+				t.Fatalf("Run() error = %v", err)
+			}
+
+			got := []string{}
+			for _, rec := range c.Records() {
+				if rec.Asset {
+					rel, err := filepath.Rel(dir, rec.Path)
+					if err != nil {
+						t.Fatal(err)
+					}
+					got = append(got, rel)
+				}
+			}
+			sort.Strings(got)
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("Run() saved assets %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,114 @@
+package crawler
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"time"
+)
+
+// ErrHostDown is returned for a request not sent because its host failed
+// MaxHostFailures times in a row and is cooling down.
+var ErrHostDown = errors.New("host is down, skipped until its cooldown ends")
+
+// TripStats counts how often the circuit breaker of a host tripped and the
+// requests it refused while open.
+type TripStats struct {
+	Trips   int64 `json:"trips"`
+	Skipped int64 `json:"skipped"`
+}
+
+// breaker is the circuit breaker of a host. It is closed while openUntil is
+// zero. Once that time is past, a single probe request is let through: it
+// closes the breaker if it gets a response and opens it again otherwise.
+type breaker struct {
+	failures  int
+	openUntil time.Time
+	probing   bool
+	trips     int64
+	skipped   int64
+}
+
+// hostBreakers holds the circuit breaker of every host a request to failed.
+type hostBreakers struct {
+	mutex sync.Mutex
+	hosts map[string]*breaker
+}
+
+// allow reports whether a request to host may be sent at now.
+func (b *hostBreakers) allow(host string, now time.Time) bool {
+	b.mutex.Lock()
+	defer b.mutex.Unlock()
+
+	br := b.hosts[host]
+	if br == nil || br.openUntil.IsZero() {
+		return true
+	}
+	if now.Before(br.openUntil) || br.probing {
+		br.skipped++
+		return false
+	}
+
+	br.probing = true
+
+	return true
+}
+
+// record counts the outcome of a request to host, reporting whether it
+// tripped the breaker, open for cooldown from now on.
+func (b *hostBreakers) record(host string, failed bool, threshold int, cooldown time.Duration, now time.Time) bool {
+	b.mutex.Lock()
+	defer b.mutex.Unlock()
+
+	br := b.hosts[host]
+	if !failed {
+		if br != nil {
+			br.failures, br.openUntil, br.probing = 0, time.Time{}, false
+		}
+		return false
+	}
+
+	if br == nil {
+		if b.hosts == nil {
+			b.hosts = map[string]*breaker{}
+		}
+		br = &breaker{}
+		b.hosts[host] = br
+	}
+
+	// requests sent before it opened are already counted
+	if !br.openUntil.IsZero() && !br.probing {
+		return false
+	}
+
+	br.failures++
+	if !br.probing && br.failures < threshold {
+		return false
+	}
+
+	br.failures, br.openUntil, br.probing = 0, now.Add(cooldown), false
+	br.trips++
+
+	return true
+}
+
+func (b *hostBreakers) stats() map[string]TripStats {
+	b.mutex.Lock()
+	defer b.mutex.Unlock()
+
+	st := map[string]TripStats{}
+	for host, br := range b.hosts {
+		if br.trips > 0 {
+			st[host] = TripStats{Trips: br.trips, Skipped: br.skipped}
+		}
+	}
+
+	return st
+}
+
+// hostFailed reports whether err, returned for a request that got no
+// response, counts against the breaker of its host: a cancelled crawl or a
+// redirect loop doesn't.
+func hostFailed(ctx context.Context, err error) bool {
+	return err != nil && ctx.Err() == nil && !errors.Is(err, errTooManyRedirects) && !errors.Is(err, errRedirectLoop)
+}
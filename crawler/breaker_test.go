@@ -0,0 +1,119 @@
+package crawler
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+)
+
+func Test_hostBreakers(t *testing.T) {
+	type step struct {
+		at     time.Duration
+		failed bool
+		want   bool
+	}
+	tests := []struct {
+		name      string
+		steps     []step
+		wantStats map[string]TripStats
+	}{
+		{
+			name:      "Test failures below the threshold keep it closed",
+			steps:     []step{{failed: true, want: true}, {failed: false, want: true}, {failed: true, want: true}, {failed: true, want: true}},
+			wantStats: map[string]TripStats{},
+		},
+		{
+			name: "Test consecutive failures open it until the cooldown",
+			steps: []step{
+				{failed: true, want: true},
+				{failed: true, want: true},
+				{failed: true, want: true},
+				{at: time.Second, want: false},
+				{at: time.Minute, want: true},
+				{at: time.Minute, want: true},
+			},
+			wantStats: map[string]TripStats{"example.com": {Trips: 1, Skipped: 1}},
+		},
+		{
+			name: "Test failed probe opens it again",
+			steps: []step{
+				{failed: true, want: true},
+				{failed: true, want: true},
+				{failed: true, want: true},
+				{at: time.Minute, failed: true, want: true},
+				{at: time.Minute + time.Second, want: false},
+				{at: 2*time.Minute + time.Second, want: true},
+			},
+			wantStats: map[string]TripStats{"example.com": {Trips: 2, Skipped: 1}},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var b hostBreakers
+			start := time.Now()
+			for i, s := range tt.steps {
+				now := start.Add(s.at)
+				if got := b.allow("example.com", now); got != s.want {
+					t.Fatalf("step %v: allow() = %v, want %v", i, got, s.want)
+				}
+				if s.want {
+					b.record("example.com", s.failed, 3, time.Minute, now)
+				}
+			}
+
+			if got := b.stats(); !reflect.DeepEqual(got, tt.wantStats) {
+				t.Errorf("stats() = %v, want %v", got, tt.wantStats)
+			}
+		})
+	}
+}
+
+func TestCrawler_RunHostBreaker(t *testing.T) {
+	dead := httptest.NewServer(http.NotFoundHandler())
+	deadURL := dead.URL
+	dead.Close()
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var links strings.Builder
+		for i := 0; i < 5; i++ {
+			fmt.Fprintf(&links, `<a href="%v/docs/%v">%v</a>`, deadURL, i, i)
+		}
+		fmt.Fprint(w, "<p>"+links.String()+"</p>")
+	}))
+	defer server.Close()
+
+	c := New(server.URL+"/docs", t.TempDir())
+	c.IgnoreRobots = true
+	c.Sequential = true
+	c.Retries = 0
+	c.AllowedDomains = []string{"127.0.0.1"}
+	c.Scope = ScopeHost
+	c.MaxHostFailures = 2
+	c.HostCooldown = time.Hour
+	var pageErrs PageErrors
+	if err := c.Run(context.Background()); !errors.As(err, &pageErrs) || len(pageErrs) != 5 {
+		t.Fatalf("Run() error = %v, want the 5 pages of the dead host", err)
+	}
+	if err := pageErrs[4]; !errors.Is(err, ErrHostDown) {
+		t.Errorf("last error = %v, want %v", err, ErrHostDown)
+	}
+
+	host := strings.TrimPrefix(deadURL, "http://")
+	st := c.Stats()
+	if want := map[string]TripStats{host: {Trips: 1, Skipped: 3}}; !reflect.DeepEqual(st.Breakers, want) {
+		t.Errorf("Stats().Breakers = %v, want %v", st.Breakers, want)
+	}
+
+	var buf bytes.Buffer
+	st.Print(&buf)
+	if line := "  " + host + ": 1 times, 3 requests skipped\n"; !strings.Contains(buf.String(), line) {
+		t.Errorf("Print() = %q, want a line %q", buf.String(), line)
+	}
+}
@@ -0,0 +1,105 @@
+package crawler
+
+import (
+	"bytes"
+	"fmt"
+	"net/url"
+	"os"
+	"sort"
+	"sync"
+)
+
+// referrers collects the pages linking to every url, keyed like the
+// records so a failed one can be traced back to them.
+type referrers struct {
+	mutex sync.Mutex
+	pages map[string]map[string]struct{}
+}
+
+func (r *referrers) add(from string, to []string) {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+
+	if r.pages == nil {
+		r.pages = map[string]map[string]struct{}{}
+	}
+	for _, u := range to {
+		if r.pages[u] == nil {
+			r.pages[u] = map[string]struct{}{}
+		}
+		r.pages[u][from] = struct{}{}
+	}
+}
+
+// of returns the pages linking to u, sorted.
+func (r *referrers) of(u string) []string {
+	r.mutex.Lock()
+	pages := make([]string, 0, len(r.pages[u]))
+	for page := range r.pages[u] {
+		pages = append(pages, page)
+	}
+	r.mutex.Unlock()
+
+	sort.Strings(pages)
+
+	return pages
+}
+
+// addReferrers records that the page or stylesheet from, a record key,
+// needs assets, with Referrers set. Links to pages are recorded by
+// extractUrls.
+func (c *Crawler) addReferrers(from string, assets []string) {
+	if !c.Referrers {
+		return
+	}
+
+	keys := make([]string, 0, len(assets))
+	for _, u := range assets {
+		keys = append(keys, c.recordKey(u, true))
+	}
+	c.referrers.add(from, keys)
+}
+
+// recordKey returns the URL the record of the page or asset u is kept
+// under, with the case of its path under CaseInsensitivePaths.
+func (c *Crawler) recordKey(u string, asset bool) string {
+	parsed, err := url.Parse(u)
+	if err != nil {
+		return u
+	}
+	if asset {
+		parsed.RawQuery = ""
+		return normalizeURL(parsed, c.LowercasePaths).String()
+	}
+
+	return c.pageRequest(parsed)
+}
+
+// broken reports whether rec got an answer other than 2xx, or a 304 for a
+// copy that is still current.
+func broken(rec Record) bool {
+	if rec.StatusCode == 0 || rec.StatusCode == 304 {
+		return false
+	}
+
+	return rec.StatusCode < 200 || rec.StatusCode > 299
+}
+
+// WriteBrokenLinks writes every url that was answered with a status other
+// than 2xx to fileName, followed by the pages linking to it, one per
+// indented line. Those are only known with Referrers set.
+func (c *Crawler) WriteBrokenLinks(fileName string) error {
+	var buf bytes.Buffer
+	for _, rec := range c.Records() {
+		if !broken(rec) {
+			continue
+		}
+
+		fmt.Fprintf(&buf, "%v %v\n", rec.StatusCode, rec.URL)
+		for _, page := range c.referrers.of(rec.URL) {
+			fmt.Fprintf(&buf, "\t%v\n", page)
+		}
+	}
+
+	return os.WriteFile(fileName, buf.Bytes(), 0644)
+}
@@ -0,0 +1,121 @@
+package crawler
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestCrawler_WriteBrokenLinks(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/docs":
+			fmt.Fprint(w, `<a href="/docs/a">a</a><a href="/docs/missing">missing</a>`)
+		case "/docs/a":
+			fmt.Fprint(w, `<a href="/docs/missing#top">missing</a><img src="/docs/gone.png">`)
+		case "/docs/gone.png":
+			http.Error(w, "gone", http.StatusGone)
+		default:
+			http.NotFound(w, r)
+		}
+	}))
+	defer server.Close()
+
+	tests := []struct {
+		name      string
+		referrers bool
+		want      []string
+	}{
+		{
+			name:      "Test broken links are listed with the pages linking to them",
+			referrers: true,
+			want: []string{
+				`410 URL/docs/gone.png`,
+				"\tURL/docs/a",
+				`404 URL/docs/missing`,
+				"\tURL/docs",
+				"\tURL/docs/a",
+			},
+		},
+		{
+			name: "Test referrers aren't recorded by default",
+			want: []string{
+				`410 URL/docs/gone.png`,
+				`404 URL/docs/missing`,
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			c := New(server.URL+"/docs", t.TempDir())
+			c.IgnoreRobots = true
+			c.Assets = true
+			c.Retries = 0
+			c.Referrers = tt.referrers
+			c.Run(context.Background())
+
+			fileName := filepath.Join(t.TempDir(), "broken.txt")
+			if err := c.WriteBrokenLinks(fileName); err != nil {
+				t.Fatalf("WriteBrokenLinks() error = %v", err)
+			}
+
+			data, err := os.ReadFile(fileName)
+			if err != nil {
+				t.Fatal(err)
+			}
+			want := strings.ReplaceAll(strings.Join(tt.want, "\n")+"\n", "URL", server.URL)
+			if string(data) != want {
+				t.Errorf("WriteBrokenLinks() wrote\n%v\nwant\n%v", string(data), want)
+			}
+		})
+	}
+}
+
+func TestCrawler_WriteBrokenLinksCaseInsensitivePaths(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch strings.ToLower(r.URL.Path) {
+		case "/docs":
+			fmt.Fprint(w, `<a href="/docs/Guide">guide</a>`)
+		case "/docs/guide":
+			fmt.Fprint(w, `<a href="/docs/Guide/Missing">missing</a><img src="/docs/Guide/Gone.png">`)
+		case "/docs/guide/gone.png":
+			http.Error(w, "gone", http.StatusGone)
+		default:
+			http.NotFound(w, r)
+		}
+	}))
+	defer server.Close()
+
+	c := New(server.URL+"/docs", t.TempDir())
+	c.IgnoreRobots = true
+	c.Assets = true
+	c.Retries = 0
+	c.Referrers = true
+	c.CaseInsensitivePaths = true
+	c.Run(context.Background())
+
+	fileName := filepath.Join(t.TempDir(), "broken.txt")
+	if err := c.WriteBrokenLinks(fileName); err != nil {
+		t.Fatalf("WriteBrokenLinks() error = %v", err)
+	}
+
+	data, err := os.ReadFile(fileName)
+	if err != nil {
+		t.Fatal(err)
+	}
+	// the pages linking are listed as they were reported
+	want := strings.ReplaceAll(strings.Join([]string{
+		`410 URL/docs/Guide/Gone.png`,
+		"\tURL/docs/Guide",
+		`404 URL/docs/Guide/Missing`,
+		"\tURL/docs/Guide",
+	}, "\n")+"\n", "URL", server.URL)
+	if string(data) != want {
+		t.Errorf("WriteBrokenLinks() wrote\n%v\nwant\n%v", string(data), want)
+	}
+}
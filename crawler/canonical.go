@@ -0,0 +1,74 @@
+package crawler
+
+import (
+	"net/url"
+	"strings"
+
+	"golang.org/x/net/html"
+)
+
+// canonicalURL returns the url declared by the page's
+// <link rel="canonical">, resolved against its base, or nil when there is
+// none.
+func canonicalURL(htmlDoc *html.Node, pageURL *url.URL) *url.URL {
+	href := ""
+
+	var f func(*html.Node)
+	f = func(n *html.Node) {
+		if href != "" {
+			return
+		}
+		if n.Type == html.ElementNode && n.Data == "link" {
+			rel, h := "", ""
+			for _, a := range n.Attr {
+				switch a.Key {
+				case "rel":
+					rel = strings.ToLower(a.Val)
+				case "href":
+					h = strings.TrimSpace(a.Val)
+				}
+			}
+			for _, r := range strings.Fields(rel) {
+				if r == "canonical" {
+					href = h
+				}
+			}
+		}
+		for child := n.FirstChild; child != nil; child = child.NextSibling {
+			f(child)
+		}
+	}
+	f(htmlDoc)
+
+	if href == "" {
+		return nil
+	}
+	u, err := url.Parse(href)
+	if err != nil {
+		return nil
+	}
+	u = findBase(htmlDoc, pageURL).ResolveReference(u)
+	if u.Scheme != "http" && u.Scheme != "https" {
+		return nil
+	}
+
+	return u
+}
+
+// canonical logs when the page fetched from pageURL declares another
+// canonical url, and returns it when it is on the same origin, so it can
+// stand for the page.
+func (c *Crawler) canonical(htmlDoc *html.Node, pageURL *url.URL) *url.URL {
+	canon := canonicalURL(htmlDoc, pageURL)
+	if canon == nil || c.pageKey(canon) == c.pageKey(pageURL) {
+		return nil
+	}
+	c.Logger.Info("page declares another canonical url", "url", pageURL.String(), "canonical", canon.String())
+
+	n, p := normalizeURL(canon, false), normalizeURL(pageURL, false)
+	if n.Scheme != p.Scheme || n.Host != p.Host {
+		return nil
+	}
+
+	return canon
+}
@@ -0,0 +1,120 @@
+package crawler
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"path/filepath"
+	"reflect"
+	"sort"
+	"strings"
+	"testing"
+)
+
+func Test_canonicalURL(t *testing.T) {
+	pageURL, _ := url.Parse("https://example.com/docs/print")
+
+	tests := []struct {
+		name string
+		page string
+		want string
+	}{
+		{
+			name: "Test relative canonical is resolved",
+			page: `<head><link rel="canonical" href="page"></head>`,
+			want: "https://example.com/docs/page",
+		},
+		{
+			name: "Test canonical is resolved against the base",
+			page: `<head><base href="/other/"><link rel="Canonical" href="page"></head>`,
+			want: "https://example.com/other/page",
+		},
+		{
+			name: "Test other link relations are ignored",
+			page: `<head><link rel="stylesheet" href="style.css"><link rel="alternate" href="/fr/page"></head>`,
+		},
+		{
+			name: "Test non-http canonical is ignored",
+			page: `<head><link rel="canonical" href="mailto:someone@example.com"></head>`,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			doc, err := parseHTML([]byte(tt.page))
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			got := ""
+			if u := canonicalURL(doc, pageURL); u != nil {
+				got = u.String()
+			}
+			if got != tt.want {
+				t.Errorf("canonicalURL() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestCrawler_RunCanonical(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/docs":
+			fmt.Fprint(w, `<html><body><a href="/docs/print">print</a><a href="/docs/page">page</a><a href="/docs/far">far</a></body></html>`)
+		case "/docs/print", "/docs/page":
+			fmt.Fprint(w, `<html><head><link rel="canonical" href="/docs/page"></head><body>page</body></html>`)
+		case "/docs/far":
+			fmt.Fprint(w, `<html><head><link rel="canonical" href="https://other.example/docs/far"></head><body>far</body></html>`)
+		default:
+			http.NotFound(w, r)
+		}
+	}))
+	defer server.Close()
+
+	tests := []struct {
+		name      string
+		canonical bool
+		want      []string
+	}{
+		{
+			name: "Test canonical links are ignored by default",
+			want: []string{"docs/docs.html", "docs/far/far.html", "docs/page/page.html", "docs/print/print.html"},
+		},
+		{
+			name:      "Test same-origin canonical urls dedup pages",
+			canonical: true,
+			want:      []string{"docs/docs.html", "docs/far/far.html", "docs/page/page.html"},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			dir := t.TempDir()
+			c := New(server.URL+"/docs", dir)
+			c.IgnoreRobots = true
+			c.HonorCanonical = tt.canonical
+			if err := c.Run(context.Background()); err != nil {
+				t.Fatalf("Run() error = %v", err)
+			}
+
+			got := []string{}
+			for _, rec := range c.Records() {
+				if rec.Path != "" {
+					rel, err := filepath.Rel(dir, rec.Path)
+					if err != nil {
+						t.Fatal(err)
+					}
+					got = append(got, filepath.ToSlash(rel))
+				}
+				if tt.canonical && strings.HasSuffix(rec.URL, "/docs/print") && rec.Canonical != server.URL+"/docs/page" {
+					t.Errorf("Record(%v).Canonical = %q, want %q", rec.URL, rec.Canonical, server.URL+"/docs/page")
+				}
+			}
+			sort.Strings(got)
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("Run() saved %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
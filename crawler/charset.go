@@ -0,0 +1,38 @@
+package crawler
+
+import (
+	"regexp"
+
+	"golang.org/x/net/html/charset"
+	"golang.org/x/text/encoding"
+)
+
+// metaCharset matches the charset declared by a <meta> tag, either as
+// <meta charset="..."> or inside an http-equiv Content-Type.
+var metaCharset = regexp.MustCompile(`(?i)(<meta[^>]*charset\s*=\s*["']?)([\w.:-]+)`)
+
+// toUTF8 transcodes a page to UTF-8 using the charset of its Content-Type
+// header or, failing that, of its <meta> tags. The declaration in the page
+// is updated so the saved copy still opens correctly. Pages already in
+// UTF-8, or in a charset that can't be decoded, are returned as is, and
+// transcoded reports false.
+func (c *Crawler) toUTF8(content []byte, contentType string) (_ []byte, transcoded bool) {
+	enc, name, _ := charset.DetermineEncoding(content, contentType)
+	if name == "utf-8" || enc == encoding.Nop {
+		return content, false
+	}
+
+	decoded, err := enc.NewDecoder().Bytes(content)
+	if err != nil {
+		c.Logger.Warn("error transcoding to utf-8", "charset", name, "err", err)
+		return content, false
+	}
+
+	return metaCharset.ReplaceAll(decoded, []byte("${1}utf-8")), true
+}
+
+// utf8ContentType is the Content-Type of content once toUTF8 transcoded
+// it: its media type with a utf-8 charset.
+func utf8ContentType(contentType string, content []byte) string {
+	return mediaType(contentType, content) + "; charset=utf-8"
+}
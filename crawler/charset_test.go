@@ -0,0 +1,53 @@
+package crawler
+
+import (
+	"testing"
+)
+
+func Test_toUTF8(t *testing.T) {
+	// "café" and "naïve" in ISO-8859-1
+	latin1 := []byte("<html><head><meta charset=\"iso-8859-1\"></head><body>caf\xe9 na\xefve</body></html>")
+
+	type args struct {
+		content     []byte
+		contentType string
+	}
+	tests := []struct {
+		name       string
+		args       args
+		want       string
+		transcoded bool
+	}{
+		{
+			name: "Test charset from the Content-Type header",
+			args: args{
+				content:     []byte("<p>caf\xe9</p>"),
+				contentType: "text/html; charset=ISO-8859-1",
+			},
+			want:       "<p>café</p>",
+			transcoded: true,
+		},
+		{
+			name:       "Test charset from the meta tag",
+			args:       args{content: latin1, contentType: "text/html"},
+			want:       `<html><head><meta charset="utf-8"></head><body>café naïve</body></html>`,
+			transcoded: true,
+		},
+		{
+			name: "Test utf-8 is left alone",
+			args: args{content: []byte("<p>café</p>"), contentType: "text/html; charset=utf-8"},
+			want: "<p>café</p>",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, transcoded := New("https://example.com", t.TempDir()).toUTF8(tt.args.content, tt.args.contentType)
+			if string(got) != tt.want {
+				t.Errorf("toUTF8() = %q, want %q", got, tt.want)
+			}
+			if transcoded != tt.transcoded {
+				t.Errorf("toUTF8() transcoded = %v, want %v", transcoded, tt.transcoded)
+			}
+		})
+	}
+}
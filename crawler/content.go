@@ -0,0 +1,45 @@
+package crawler
+
+import (
+	"mime"
+	"net/http"
+	"strings"
+)
+
+// mediaType returns the lowercased media type of a response, without its
+// parameters. A missing Content-Type is sniffed from the body.
+func mediaType(contentType string, body []byte) string {
+	if contentType == "" {
+		contentType = http.DetectContentType(body)
+	}
+
+	t, _, err := mime.ParseMediaType(contentType)
+	if err != nil {
+		return strings.ToLower(strings.TrimSpace(strings.Split(contentType, ";")[0]))
+	}
+
+	return t
+}
+
+// isHTML reports whether a media type is parsed for links.
+func isHTML(t string) bool {
+	return t == "text/html" || t == "application/xhtml+xml"
+}
+
+// accepted reports whether pages of media type t are kept. Accept entries
+// are either exact media types or "type/*" wildcards; no entries accepts
+// everything.
+func (c *Crawler) accepted(t string) bool {
+	if len(c.Accept) == 0 {
+		return true
+	}
+
+	for _, a := range c.Accept {
+		a = strings.ToLower(strings.TrimSpace(a))
+		if a == t || a == "*/*" || (strings.HasSuffix(a, "/*") && strings.HasPrefix(t, strings.TrimSuffix(a, "*"))) {
+			return true
+		}
+	}
+
+	return false
+}
@@ -0,0 +1,159 @@
+package crawler
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"reflect"
+	"sync"
+	"testing"
+)
+
+func TestCrawler_RunContentTypes(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/docs":
+			fmt.Fprint(w, `<a href="/docs/file.pdf">pdf</a><a href="/docs/notes">notes</a>`)
+		case "/docs/file.pdf":
+			w.Header().Set("Content-Type", "application/pdf")
+			fmt.Fprint(w, `%PDF-1.4 <a href="/docs/hidden">not a link</a>`)
+		case "/docs/notes":
+			w.Header().Set("Content-Type", "text/plain; charset=utf-8")
+			fmt.Fprint(w, `notes`)
+		default:
+			http.NotFound(w, r)
+		}
+	}))
+	defer server.Close()
+
+	type args struct {
+		assets bool
+		accept []string
+	}
+	tests := []struct {
+		name string
+		args args
+		want []string
+	}{
+		{
+			name: "Test non-html pages are skipped",
+			want: []string{filepath.Join("docs", "docs.html"), filepath.Join("docs", "docs.meta.json")},
+		},
+		{
+			name: "Test non-html pages are saved but not parsed with assets",
+			args: args{assets: true},
+			want: []string{
+				filepath.Join("docs", "docs.html"),
+				filepath.Join("docs", "docs.meta.json"),
+				filepath.Join("docs", "file.pdf"),
+				filepath.Join("docs", "notes"),
+			},
+		},
+		{
+			name: "Test accept filters content types",
+			args: args{assets: true, accept: []string{"text/*", "TEXT/HTML"}},
+			want: []string{
+				filepath.Join("docs", "docs.html"),
+				filepath.Join("docs", "docs.meta.json"),
+				filepath.Join("docs", "notes"),
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			dir := t.TempDir()
+			c := New(server.URL+"/docs", dir)
+			c.IgnoreRobots = true
+			c.Assets = tt.args.assets
+			c.Accept = tt.args.accept
+			if err := c.Run(context.Background()); err != nil {
+				t.Fatalf("Run() error = %v", err)
+			}
+
+			files := []string{}
+			err := filepath.WalkDir(dir, func(p string, d os.DirEntry, err error) error {
+				if err == nil && !d.IsDir() {
+					rel, _ := filepath.Rel(dir, p)
+					files = append(files, rel)
+				}
+				return err
+			})
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			if !reflect.DeepEqual(files, tt.want) {
+				t.Errorf("saved files = %v, want %v", files, tt.want)
+			}
+		})
+	}
+}
+
+func TestCrawler_RunHeadFirst(t *testing.T) {
+	var mutex sync.Mutex
+	gets := map[string]int{}
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method == http.MethodGet {
+			mutex.Lock()
+			gets[r.URL.Path]++
+			mutex.Unlock()
+		}
+
+		switch r.URL.Path {
+		case "/docs":
+			fmt.Fprint(w, `<a href="/docs/file.pdf">pdf</a><a href="/docs/legacy">legacy</a>`)
+		case "/docs/file.pdf":
+			w.Header().Set("Content-Type", "application/pdf")
+			fmt.Fprint(w, `%PDF-1.4`)
+		case "/docs/legacy":
+			if r.Method == http.MethodHead {
+				http.Error(w, "no HEAD here", http.StatusMethodNotAllowed)
+				return
+			}
+			fmt.Fprint(w, `<p>legacy</p>`)
+		default:
+			http.NotFound(w, r)
+		}
+	}))
+	defer server.Close()
+
+	tests := []struct {
+		name     string
+		assets   bool
+		wantGets map[string]int
+	}{
+		{
+			name:     "Test unwanted types aren't downloaded",
+			wantGets: map[string]int{"/docs": 1, "/docs/legacy": 1},
+		},
+		{
+			name:     "Test wanted types are downloaded",
+			assets:   true,
+			wantGets: map[string]int{"/docs": 1, "/docs/file.pdf": 1, "/docs/legacy": 1},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			mutex.Lock()
+			gets = map[string]int{}
+			mutex.Unlock()
+
+			c := New(server.URL+"/docs", t.TempDir())
+			c.IgnoreRobots = true
+			c.HeadFirst = true
+			c.Assets = tt.assets
+			if err := c.Run(context.Background()); err != nil {
+				t.Fatalf("Run() error = %v", err)
+			}
+
+			mutex.Lock()
+			defer mutex.Unlock()
+			if !reflect.DeepEqual(gets, tt.wantGets) {
+				t.Errorf("Run() sent GET requests %v, want %v", gets, tt.wantGets)
+			}
+		})
+	}
+}
@@ -0,0 +1,99 @@
+package crawler
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/cookiejar"
+	"net/url"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+
+	"golang.org/x/net/publicsuffix"
+)
+
+// newJar builds the cookie jar shared by the whole crawl, preloaded with
+// Cookies and the content of CookieFile.
+func (c *Crawler) newJar() (http.CookieJar, error) {
+	jar, err := cookiejar.New(&cookiejar.Options{PublicSuffixList: publicsuffix.List})
+	if err != nil {
+		return nil, err
+	}
+
+	if len(c.Cookies) > 0 {
+		target, err := url.Parse(c.target)
+		if err != nil {
+			return nil, err
+		}
+		jar.SetCookies(target, c.Cookies)
+	}
+
+	if c.CookieFile != "" {
+		file, err := os.Open(c.CookieFile)
+		if err != nil {
+			return nil, err
+		}
+		defer file.Close()
+
+		if err := loadCookieFile(jar, file); err != nil {
+			return nil, fmt.Errorf("%v: %w", c.CookieFile, err)
+		}
+	}
+
+	return jar, nil
+}
+
+// loadCookieFile adds the cookies of a Netscape cookies.txt file to jar.
+// Each line holds domain, include subdomains, path, secure, expiry, name
+// and value separated by tabs.
+func loadCookieFile(jar http.CookieJar, r io.Reader) error {
+	scanner := bufio.NewScanner(r)
+	for n := 1; scanner.Scan(); n++ {
+		line := strings.TrimSpace(scanner.Text())
+
+		// curl marks HttpOnly cookies with a prefix that looks like a comment
+		httpOnly := strings.HasPrefix(line, "#HttpOnly_")
+		line = strings.TrimPrefix(line, "#HttpOnly_")
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+
+		fields := strings.Split(line, "\t")
+		if len(fields) != 7 {
+			return fmt.Errorf("line %v: expected 7 fields, got %v", n, len(fields))
+		}
+
+		host := strings.TrimPrefix(fields[0], ".")
+		secure := strings.EqualFold(fields[3], "TRUE")
+		cookie := &http.Cookie{
+			Name:     fields[5],
+			Value:    fields[6],
+			Path:     fields[2],
+			Secure:   secure,
+			HttpOnly: httpOnly,
+		}
+		if strings.EqualFold(fields[1], "TRUE") {
+			cookie.Domain = host
+		}
+
+		// zero means a session cookie
+		expires, err := strconv.ParseInt(fields[4], 10, 64)
+		if err != nil {
+			return fmt.Errorf("line %v: invalid expiry %q", n, fields[4])
+		}
+		if expires > 0 {
+			cookie.Expires = time.Unix(expires, 0)
+		}
+
+		scheme := "http"
+		if secure {
+			scheme = "https"
+		}
+		jar.SetCookies(&url.URL{Scheme: scheme, Host: host, Path: "/"}, []*http.Cookie{cookie})
+	}
+
+	return scanner.Err()
+}
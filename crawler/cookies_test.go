@@ -0,0 +1,117 @@
+package crawler
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/http/cookiejar"
+	"net/http/httptest"
+	"net/url"
+	"reflect"
+	"sort"
+	"strings"
+	"testing"
+)
+
+func Test_loadCookieFile(t *testing.T) {
+	cookies := strings.Join([]string{
+		"# Netscape HTTP Cookie File",
+		"",
+		".example.com\tTRUE\t/\tFALSE\t0\tshared\t1",
+		"www.example.com\tFALSE\t/\tTRUE\t4102444800\tsecure\t2",
+		"#HttpOnly_www.example.com\tFALSE\t/\tFALSE\t0\thidden\t3",
+		"www.example.com\tFALSE\t/\tFALSE\t1\texpired\t4",
+	}, "\n")
+
+	tests := []struct {
+		name string
+		url  string
+		want []string
+	}{
+		{
+			name: "Test cookies over https",
+			url:  "https://www.example.com/",
+			want: []string{"hidden=3", "secure=2", "shared=1"},
+		},
+		{
+			name: "Test secure cookies are not sent over http",
+			url:  "http://www.example.com/",
+			want: []string{"hidden=3", "shared=1"},
+		},
+		{
+			name: "Test domain cookies reach subdomains",
+			url:  "http://blog.example.com/",
+			want: []string{"shared=1"},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			jar, err := cookiejar.New(nil)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if err := loadCookieFile(jar, strings.NewReader(cookies)); err != nil {
+				t.Fatalf("loadCookieFile() error = %v", err)
+			}
+
+			u, _ := url.Parse(tt.url)
+			got := []string{}
+			for _, cookie := range jar.Cookies(u) {
+				got = append(got, cookie.String())
+			}
+			sort.Strings(got)
+
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("Cookies(%v) = %v, want %v", tt.url, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestCrawler_RunCookies(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/app":
+			if _, err := r.Cookie("token"); err != nil {
+				http.Error(w, "no token", http.StatusForbidden)
+				return
+			}
+			http.SetCookie(w, &http.Cookie{Name: "session", Value: "1", Path: "/"})
+			fmt.Fprint(w, `<a href="/app/private">private</a>`)
+		case "/app/private":
+			if _, err := r.Cookie("session"); err != nil {
+				http.Error(w, "no session", http.StatusForbidden)
+				return
+			}
+			fmt.Fprint(w, `<p>private</p>`)
+		default:
+			http.NotFound(w, r)
+		}
+	}))
+	defer server.Close()
+
+	tests := []struct {
+		name    string
+		cookies []*http.Cookie
+		wantErr bool
+	}{
+		{
+			name:    "Test session cookie is kept across requests",
+			cookies: []*http.Cookie{{Name: "token", Value: "abc"}},
+		},
+		{
+			name:    "Test missing cookie",
+			wantErr: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			c := New(server.URL+"/app", t.TempDir())
+			c.IgnoreRobots = true
+			c.Cookies = tt.cookies
+			if err := c.Run(context.Background()); (err != nil) != tt.wantErr {
+				t.Errorf("Run() error = %v, wantErr %v", err, tt.wantErr)
+			}
+		})
+	}
+}
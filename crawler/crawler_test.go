@@ -0,0 +1,1619 @@
+package crawler
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"os"
+	"path"
+	"path/filepath"
+	"reflect"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"golang.org/x/net/html"
+)
+
+func TestCrawler_Run(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/features":
+			fmt.Fprint(w, `<a href="/features/actions">actions</a>`)
+		case "/features/actions":
+			fmt.Fprint(w, `<a href="/features">back</a>`)
+		case "/broken":
+			fmt.Fprint(w, `<a href="/broken/missing">missing</a>`)
+		default:
+			http.NotFound(w, r)
+		}
+	}))
+	defer server.Close()
+
+	// a regular file can't be used as the output directory
+	notADir := filepath.Join(t.TempDir(), "file")
+	if err := os.WriteFile(notADir, nil, 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	type args struct {
+		target string
+		dir    string
+	}
+	tests := []struct {
+		name        string
+		args        args
+		wantErr     bool
+		wantPageErr bool
+	}{
+		{
+			name: "Test successful execution",
+			args: args{
+				target: server.URL + "/features",
+				dir:    t.TempDir(),
+			},
+			wantErr: false,
+		},
+		{
+			name: "Test missing page is a page error",
+			args: args{
+				target: server.URL + "/broken",
+				dir:    t.TempDir(),
+			},
+			wantErr:     true,
+			wantPageErr: true,
+		},
+		{
+			name: "Test unusable output dir is fatal",
+			args: args{
+				target: server.URL + "/features",
+				dir:    notADir,
+			},
+			wantErr:     true,
+			wantPageErr: false,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			c := New(tt.args.target, tt.args.dir)
+			err := c.Run(context.Background())
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("Run() error = %v, wantErr %v", err, tt.wantErr)
+			}
+
+			var pageErrs PageErrors
+			if gotPageErr := errors.As(err, &pageErrs); gotPageErr != tt.wantPageErr {
+				t.Errorf("Run() error = %v, wantPageErr %v", err, tt.wantPageErr)
+			}
+		})
+	}
+}
+
+func TestCrawler_RunDeadline(t *testing.T) {
+	var mutex sync.Mutex
+	hits := map[string]int{}
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		mutex.Lock()
+		hits[r.URL.Path]++
+		mutex.Unlock()
+
+		switch r.URL.Path {
+		case "/docs":
+			fmt.Fprint(w, `<a href="/docs/slow">slow</a><a href="/docs/next">next</a>`)
+		case "/docs/slow":
+			// hang until the crawler gives up on the request
+			<-r.Context().Done()
+		default:
+			fmt.Fprint(w, `<p>page</p>`)
+		}
+	}))
+	defer server.Close()
+
+	tests := []struct {
+		name        string
+		deadline    time.Duration
+		maxDuration time.Duration
+		wantStatus  string
+		wantSkipped string
+	}{
+		{
+			name:        "Test expired crawl stops without page errors",
+			deadline:    200 * time.Millisecond,
+			wantStatus:  StatusTimeLimit,
+			wantSkipped: "/docs/next",
+		},
+		{
+			name:        "Test crawl is cut off by the time limit",
+			maxDuration: 200 * time.Millisecond,
+			wantStatus:  StatusTimeLimit,
+			wantSkipped: "/docs/next",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			c := New(server.URL+"/docs", t.TempDir())
+			c.IgnoreRobots = true
+			c.Retries = 0
+			c.Concurrency = 1
+			c.MaxDuration = tt.maxDuration
+
+			ctx := context.Background()
+			if tt.deadline > 0 {
+				var cancel context.CancelFunc
+				ctx, cancel = context.WithTimeout(ctx, tt.deadline)
+				defer cancel()
+			}
+
+			start := time.Now()
+			if err := c.Run(ctx); err != nil {
+				t.Fatalf("Run() error = %v, want nil", err)
+			}
+			if elapsed := time.Since(start); elapsed > c.Timeout {
+				t.Errorf("Run() took %v, want it cut off after %v", elapsed, tt.deadline+tt.maxDuration)
+			}
+
+			if got := c.Stats().Status; got != tt.wantStatus {
+				t.Errorf("Stats().Status = %v, want %v", got, tt.wantStatus)
+			}
+			mutex.Lock()
+			defer mutex.Unlock()
+			if hits[tt.wantSkipped] != 0 {
+				t.Errorf("%v was downloaded after the time limit", tt.wantSkipped)
+			}
+		})
+	}
+}
+
+func TestCrawler_RunOnPage(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/docs":
+			fmt.Fprint(w, `<title>Docs</title><a href="/docs/a">a</a><a href="/docs/bad">bad</a>`)
+		case "/docs/a", "/docs/bad":
+			fmt.Fprint(w, `<p>page</p>`)
+		default:
+			http.NotFound(w, r)
+		}
+	}))
+	defer server.Close()
+
+	tests := []struct {
+		name       string
+		wantCalls  []string
+		wantFailed string
+	}{
+		{
+			name:       "Test callback sees every page and its errors are recorded",
+			wantCalls:  []string{"/docs 200 Docs", "/docs/a 200 ", "/docs/bad 200 "},
+			wantFailed: "/docs/bad",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var mutex sync.Mutex
+			calls := []string{}
+
+			c := New(server.URL+"/docs", t.TempDir())
+			c.IgnoreRobots = true
+			c.OnPage = func(u string, status int, title string, body []byte, doc *html.Node) error {
+				if doc == nil || len(body) == 0 {
+					t.Errorf("OnPage(%v) got no page", u)
+				}
+
+				mutex.Lock()
+				calls = append(calls, fmt.Sprintf("%v %v %v", strings.TrimPrefix(u, server.URL), status, title))
+				mutex.Unlock()
+
+				if strings.HasSuffix(u, tt.wantFailed) {
+					return errors.New("index unavailable")
+				}
+				return nil
+			}
+			if err := c.Run(context.Background()); err != nil {
+				t.Fatalf("Run() error = %v, want callback errors kept out of it", err)
+			}
+
+			sort.Strings(calls)
+			if !reflect.DeepEqual(calls, tt.wantCalls) {
+				t.Errorf("OnPage calls = %v, want %v", calls, tt.wantCalls)
+			}
+
+			for _, rec := range c.Records() {
+				failed := strings.HasSuffix(rec.URL, tt.wantFailed)
+				if (rec.OnPageError != "") != failed {
+					t.Errorf("record of %v has OnPageError %q", rec.URL, rec.OnPageError)
+				}
+			}
+		})
+	}
+}
+
+func TestCrawler_RunLogs(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/docs":
+			fmt.Fprint(w, `<a href="/docs/missing">missing</a>`)
+		default:
+			http.NotFound(w, r)
+		}
+	}))
+	defer server.Close()
+
+	tests := []struct {
+		name  string
+		level slog.Level
+		want  []string
+	}{
+		{
+			name:  "Test info logs page results and failures",
+			level: slog.LevelInfo,
+			want: []string{
+				"level=ERROR msg=\"error downloading the target\" url=URL/docs/missing err=\"invalid status code 404\"",
+				"level=INFO msg=saved url=URL/docs",
+			},
+		},
+		{
+			name:  "Test error only logs failures",
+			level: slog.LevelError,
+			want: []string{
+				"level=ERROR msg=\"error downloading the target\" url=URL/docs/missing err=\"invalid status code 404\"",
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var buf bytes.Buffer
+			c := New(server.URL+"/docs", t.TempDir())
+			c.IgnoreRobots = true
+			c.Retries = 0
+			c.Logger = slog.New(slog.NewTextHandler(&buf, &slog.HandlerOptions{
+				Level: tt.level,
+				// drop what changes between runs
+				ReplaceAttr: func(groups []string, a slog.Attr) slog.Attr {
+					if a.Key == slog.TimeKey || a.Key == "path" {
+						return slog.Attr{}
+					}
+					return a
+				},
+			}))
+			c.Run(context.Background())
+
+			got := strings.Split(strings.TrimSpace(strings.ReplaceAll(buf.String(), server.URL, "URL")), "\n")
+			sort.Strings(got)
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("logs = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestCrawler_RunDryRun(t *testing.T) {
+	var mutex sync.Mutex
+	requests := []string{}
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		mutex.Lock()
+		requests = append(requests, r.Method+" "+r.URL.Path)
+		mutex.Unlock()
+
+		switch r.URL.Path {
+		case "/docs":
+			w.Header().Set("Content-Type", "text/html")
+			fmt.Fprint(w, `<a href="/docs/a">a</a><a href="/docs/file.pdf">pdf</a>`)
+		case "/docs/a":
+			w.Header().Set("Content-Type", "text/html")
+			fmt.Fprint(w, `<p>a</p>`)
+		case "/docs/file.pdf":
+			w.Header().Set("Content-Type", "application/pdf")
+			fmt.Fprint(w, `%PDF-1.4`)
+		default:
+			http.NotFound(w, r)
+		}
+	}))
+	defer server.Close()
+
+	tests := []struct {
+		name         string
+		wantRequests []string
+		wantURLs     []string
+	}{
+		{
+			name: "Test only html pages are downloaded and nothing is saved",
+			wantRequests: []string{
+				"GET /docs", "GET /docs/a",
+				"HEAD /docs", "HEAD /docs/a", "HEAD /docs/file.pdf",
+			},
+			wantURLs: []string{server.URL + "/docs", server.URL + "/docs/a", server.URL + "/docs/file.pdf"},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			dir := filepath.Join(t.TempDir(), "data")
+			c := New(server.URL+"/docs", dir)
+			c.IgnoreRobots = true
+			c.DryRun = true
+			if err := c.Run(context.Background()); err != nil {
+				t.Fatalf("Run() error = %v", err)
+			}
+
+			if _, err := os.Stat(dir); !errors.Is(err, os.ErrNotExist) {
+				t.Errorf("Run() created %v in a dry run", dir)
+			}
+
+			sort.Strings(requests)
+			if !reflect.DeepEqual(requests, tt.wantRequests) {
+				t.Errorf("requests = %v, want %v", requests, tt.wantRequests)
+			}
+
+			urls := []string{}
+			for _, rec := range c.Records() {
+				urls = append(urls, rec.URL)
+			}
+			if !reflect.DeepEqual(urls, tt.wantURLs) {
+				t.Errorf("Records() urls = %v, want %v", urls, tt.wantURLs)
+			}
+		})
+	}
+}
+
+func TestCrawler_RunSeeds(t *testing.T) {
+	other := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/blog", "/blog/post", "/linked":
+			fmt.Fprint(w, `<a href="/blog/post">post</a>`)
+		default:
+			http.NotFound(w, r)
+		}
+	}))
+	defer other.Close()
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/docs":
+			fmt.Fprintf(w, `<a href="/docs/a">a</a><a href="%v/linked">linked</a>`, other.URL)
+		case "/docs/a":
+			fmt.Fprint(w, `<p>a</p>`)
+		default:
+			http.NotFound(w, r)
+		}
+	}))
+	defer server.Close()
+
+	otherDir := sanitizeFileName(other.Listener.Addr().String())
+
+	type args struct {
+		seeds          []string
+		allowedDomains []string
+	}
+	tests := []struct {
+		name string
+		args args
+		want []string
+	}{
+		{
+			name: "Test seeds on other hosts are crawled and saved apart",
+			args: args{seeds: []string{other.URL + "/blog"}},
+			want: []string{
+				filepath.Join(otherDir, "blog", "blog.html"),
+				filepath.Join(otherDir, "blog", "post", "post.html"),
+				filepath.Join("docs", "a", "a.html"),
+				filepath.Join("docs", "docs.html"),
+			},
+		},
+		{
+			name: "Test allowed domains are followed across hosts",
+			args: args{allowedDomains: []string{"127.0.0.1"}},
+			want: []string{
+				filepath.Join(otherDir, "linked", "linked.html"),
+				filepath.Join("docs", "a", "a.html"),
+				filepath.Join("docs", "docs.html"),
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			dir := t.TempDir()
+			c := New(server.URL+"/docs", dir)
+			c.IgnoreRobots = true
+			c.Seeds = tt.args.seeds
+			c.AllowedDomains = tt.args.allowedDomains
+			if err := c.Run(context.Background()); err != nil {
+				t.Fatalf("Run() error = %v", err)
+			}
+
+			files := []string{}
+			for _, rec := range c.Records() {
+				rel, _ := filepath.Rel(dir, rec.Path)
+				files = append(files, rel)
+			}
+			sort.Strings(files)
+
+			if !reflect.DeepEqual(files, tt.want) {
+				t.Errorf("saved files = %v, want %v", files, tt.want)
+			}
+		})
+	}
+}
+
+func TestCrawler_RunStrategy(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/site":
+			fmt.Fprint(w, `<a href="/site/a">a</a><a href="/site/b">b</a>`)
+		case "/site/a":
+			fmt.Fprint(w, `<a href="/site/a/1">1</a><a href="/site/a/2">2</a>`)
+		case "/site/b":
+			fmt.Fprint(w, `<a href="/site/b/1">1</a>`)
+		default:
+			fmt.Fprint(w, `<p>leaf</p>`)
+		}
+	}))
+	defer server.Close()
+
+	tests := []struct {
+		name     string
+		strategy string
+		want     []string
+	}{
+		{
+			name:     "Test breadth-first visits shallow pages first",
+			strategy: StrategyBFS,
+			want:     []string{"/site", "/site/a", "/site/b"},
+		},
+		{
+			name:     "Test depth-first follows the first link down",
+			strategy: StrategyDFS,
+			want:     []string{"/site", "/site/a", "/site/a/1"},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			c := New(server.URL+"/site", t.TempDir())
+			c.IgnoreRobots = true
+			c.Strategy = tt.strategy
+			c.MaxPages = 3
+			// a single worker makes the order deterministic
+			c.Concurrency = 1
+			if err := c.Run(context.Background()); err != nil {
+				t.Fatalf("Run() error = %v", err)
+			}
+
+			got := []string{}
+			for _, rec := range c.Records() {
+				if rec.Error == "" {
+					got = append(got, strings.TrimPrefix(rec.URL, server.URL))
+				}
+			}
+
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("crawled = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestCrawler_RunSequential(t *testing.T) {
+	var mutex sync.Mutex
+	requests := []string{}
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		mutex.Lock()
+		requests = append(requests, r.URL.Path)
+		mutex.Unlock()
+
+		switch r.URL.Path {
+		case "/robots.txt":
+			fmt.Fprint(w, "User-agent: *\nDisallow: /site/private\n")
+		case "/site":
+			fmt.Fprint(w, `<p><img src="/site/logo.png"></p><a href="/site/b">b</a><a href="/site/a">a</a><a href="/site/private">private</a><a href="/site/c">c</a>`)
+		case "/site/a":
+			fmt.Fprint(w, `<a href="/site/a/2">2</a><a href="/site/a/1">1</a>`)
+		case "/site/b":
+			fmt.Fprint(w, `<a href="/site/a">a</a><a href="/site/b/1">1</a>`)
+		case "/site/logo.png":
+			w.Header().Set("Content-Type", "image/png")
+			fmt.Fprint(w, "png")
+		default:
+			fmt.Fprint(w, `<p>leaf</p>`)
+		}
+	}))
+	defer server.Close()
+
+	tests := []struct {
+		name     string
+		strategy string
+		want     []string
+	}{
+		{
+			name:     "Test depth-first requests follow the document order",
+			strategy: StrategyDFS,
+			want:     []string{"/robots.txt", "/site", "/site/logo.png", "/site/b", "/site/b/1", "/site/a", "/site/a/2", "/site/a/1", "/site/c"},
+		},
+		{
+			name:     "Test breadth-first requests follow the document order",
+			strategy: StrategyBFS,
+			want:     []string{"/robots.txt", "/site", "/site/logo.png", "/site/b", "/site/a", "/site/c", "/site/b/1", "/site/a/2", "/site/a/1"},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			// the same requests in the same order on every run
+			for run := 0; run < 3; run++ {
+				mutex.Lock()
+				requests = requests[:0]
+				mutex.Unlock()
+
+				c := New(server.URL+"/site", t.TempDir())
+				c.Strategy = tt.strategy
+				c.Assets = true
+				c.Concurrency = 10
+				c.Sequential = true
+				if err := c.Run(context.Background()); err != nil {
+					t.Fatalf("Run() error = %v", err)
+				}
+
+				mutex.Lock()
+				got := append([]string{}, requests...)
+				mutex.Unlock()
+				if !reflect.DeepEqual(got, tt.want) {
+					t.Fatalf("run %v requested %v, want %v", run, got, tt.want)
+				}
+			}
+		})
+	}
+}
+
+func TestCrawler_processConcurrent(t *testing.T) {
+	var mutex sync.Mutex
+	hits := map[string]int{}
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		mutex.Lock()
+		hits[r.URL.Path]++
+		mutex.Unlock()
+		fmt.Fprint(w, `<a href="/docs/a">a</a><a href="/docs/b">b</a>`)
+	}))
+	defer server.Close()
+
+	tests := []struct {
+		name    string
+		workers int
+		urls    []string
+	}{
+		{
+			name:    "Test concurrent workers claim each url once",
+			workers: 50,
+			urls:    []string{"/docs", "/docs/a", "/docs/b"},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			c := New(server.URL+"/docs", t.TempDir())
+			c.IgnoreRobots = true
+			client, err := c.newClient()
+			if err != nil {
+				t.Fatal(err)
+			}
+			c.client = client
+
+			// every worker races for every url at once
+			var wg sync.WaitGroup
+			for i := 0; i < tt.workers; i++ {
+				for _, u := range tt.urls {
+					wg.Add(1)
+					go func(u string) {
+						defer wg.Done()
+						if err := c.process(context.Background(), job{url: server.URL + u}); err != nil {
+							t.Errorf("process() error = %v", err)
+						}
+					}(u)
+				}
+			}
+			wg.Wait()
+
+			for _, u := range tt.urls {
+				if hits[u] != 1 {
+					t.Errorf("%v was downloaded %v times, want once", u, hits[u])
+				}
+			}
+			if got := len(c.Records()); got != len(tt.urls) {
+				t.Errorf("Records() has %v records, want %v", got, len(tt.urls))
+			}
+		})
+	}
+}
+
+func TestCrawler_RunLayout(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/" {
+			fmt.Fprint(w, `<a href="/docs/">docs</a><a href="/docs/page">page</a>`)
+			return
+		}
+		fmt.Fprint(w, `<p>page</p>`)
+	}))
+	defer server.Close()
+
+	tests := []struct {
+		name string
+		path string
+		want []string
+	}{
+		{
+			name: "Test repeated slashes make a clean layout",
+			path: "/a//b/",
+			want: []string{filepath.Join("a", "b", "index.html"), filepath.Join("a", "b", "index.meta.json")},
+		},
+		{
+			name: "Test root, directory and page don't collide",
+			path: "/",
+			want: []string{
+				filepath.Join("docs", "index.html"),
+				filepath.Join("docs", "index.meta.json"),
+				filepath.Join("docs", "page", "page.html"),
+				filepath.Join("docs", "page", "page.meta.json"),
+				"index.html",
+				"index.meta.json",
+			},
+		},
+		{
+			name: "Test page without a trailing slash is named after itself",
+			path: "/a//b",
+			want: []string{filepath.Join("a", "b", "b.html"), filepath.Join("a", "b", "b.meta.json")},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			dir := t.TempDir()
+			c := New(server.URL+tt.path, dir)
+			c.IgnoreRobots = true
+			if err := c.Run(context.Background()); err != nil {
+				t.Fatalf("Run() error = %v", err)
+			}
+
+			files := []string{}
+			err := filepath.WalkDir(dir, func(p string, d os.DirEntry, err error) error {
+				if err == nil && !d.IsDir() {
+					rel, _ := filepath.Rel(dir, p)
+					files = append(files, rel)
+				}
+				return err
+			})
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			if !reflect.DeepEqual(files, tt.want) {
+				t.Errorf("saved files = %v, want %v", files, tt.want)
+			}
+		})
+	}
+}
+
+func TestCrawler_downloadMaxSize(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body := strings.Repeat("x", 100)
+		if r.URL.Path == "/chunked" {
+			// flushing first hides the length from the client
+			w.(http.Flusher).Flush()
+		} else {
+			w.Header().Set("Content-Length", strconv.Itoa(len(body)))
+		}
+		fmt.Fprint(w, body)
+	}))
+	defer server.Close()
+
+	type args struct {
+		path    string
+		maxSize int64
+	}
+	tests := []struct {
+		name    string
+		args    args
+		wantErr bool
+	}{
+		{
+			name: "Test body within the limit",
+			args: args{path: "/sized", maxSize: 100},
+		},
+		{
+			name:    "Test content length over the limit",
+			args:    args{path: "/sized", maxSize: 99},
+			wantErr: true,
+		},
+		{
+			name:    "Test chunked body over the limit",
+			args:    args{path: "/chunked", maxSize: 99},
+			wantErr: true,
+		},
+		{
+			name: "Test no limit",
+			args: args{path: "/chunked", maxSize: 0},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			c := New(server.URL, t.TempDir())
+			c.MaxSize = tt.args.maxSize
+			client, err := c.newClient()
+			if err != nil {
+				t.Fatal(err)
+			}
+			c.client = client
+
+			_, err = c.download(context.Background(), server.URL+tt.args.path)
+			if gotErr := errors.Is(err, ErrTooLarge); gotErr != tt.wantErr || (err != nil && !gotErr) {
+				t.Errorf("download() error = %v, wantErr %v", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestCrawler_downloadRedirectLoop(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/a":
+			http.Redirect(w, r, "/a/", http.StatusFound)
+		case "/a/":
+			http.Redirect(w, r, "/b", http.StatusFound)
+		case "/b":
+			http.Redirect(w, r, "/a", http.StatusFound)
+		case "/dir":
+			http.Redirect(w, r, "/dir/", http.StatusMovedPermanently)
+		default:
+			fmt.Fprint(w, `<p>page</p>`)
+		}
+	}))
+	defer server.Close()
+
+	tests := []struct {
+		name    string
+		path    string
+		wantErr error
+	}{
+		{
+			name:    "Test chain coming back to its start is a loop",
+			path:    "/a",
+			wantErr: errRedirectLoop,
+		},
+		{
+			name: "Test redirect to the trailing slash form is followed",
+			path: "/dir",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			c := New(server.URL, t.TempDir())
+			c.Retries = 0
+			client, err := c.newClient()
+			if err != nil {
+				t.Fatal(err)
+			}
+			c.client = client
+
+			_, err = c.download(context.Background(), server.URL+tt.path)
+			if (tt.wantErr == nil && err != nil) || (tt.wantErr != nil && !errors.Is(err, tt.wantErr)) {
+				t.Errorf("download() error = %v, want %v", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestCrawler_RunPathTrap(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		// every page links to the next one, forever
+		page, _ := strconv.Atoi(r.URL.Query().Get("page"))
+		fmt.Fprintf(w, `<a href="/calendar?page=%v">next</a>`, page+1)
+	}))
+	defer server.Close()
+
+	tests := []struct {
+		name           string
+		maxPathFetches int
+		wantFetched    int
+	}{
+		{
+			name:           "Test query variants of a path are capped",
+			maxPathFetches: 5,
+			wantFetched:    5,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			c := New(server.URL+"/calendar", t.TempDir())
+			c.IgnoreRobots = true
+			c.KeepQuery = true
+			c.MaxPathFetches = tt.maxPathFetches
+			if err := c.Run(context.Background()); err != nil {
+				t.Fatalf("Run() error = %v", err)
+			}
+
+			if got := c.Stats().Pages; got != int64(tt.wantFetched) {
+				t.Errorf("Run() fetched %v pages, want %v", got, tt.wantFetched)
+			}
+		})
+	}
+}
+
+func TestCrawler_downloadHeaders(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		user, pass, ok := r.BasicAuth()
+		if !ok || user != "user" || pass != "secret" || r.Header.Get("X-Token") != "abc" {
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+		if r.Header.Get("User-Agent") != "custom" {
+			w.WriteHeader(http.StatusBadRequest)
+			return
+		}
+	}))
+	defer server.Close()
+
+	type args struct {
+		header   http.Header
+		username string
+		password string
+	}
+	tests := []struct {
+		name string
+		args args
+		want int
+	}{
+		{
+			name: "Test credentials and headers are sent",
+			args: args{
+				header:   http.Header{"X-Token": {"abc"}, "User-Agent": {"custom"}},
+				username: "user",
+				password: "secret",
+			},
+			want: http.StatusOK,
+		},
+		{
+			name: "Test missing credentials",
+			args: args{header: http.Header{"X-Token": {"abc"}}},
+			want: http.StatusUnauthorized,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			c := New(server.URL, t.TempDir())
+			c.Header = tt.args.header
+			c.Username = tt.args.username
+			c.Password = tt.args.password
+			client, err := c.newClient()
+			if err != nil {
+				t.Fatal(err)
+			}
+			c.client = client
+
+			resp, _ := c.download(context.Background(), server.URL)
+			if resp == nil || resp.status != tt.want {
+				t.Errorf("download() = %+v, want status %v", resp, tt.want)
+			}
+		})
+	}
+}
+
+func TestCrawler_downloadProxy(t *testing.T) {
+	var mutex sync.Mutex
+	proxied := []string{}
+	proxy := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		mutex.Lock()
+		proxied = append(proxied, r.URL.String())
+		mutex.Unlock()
+		fmt.Fprint(w, `<p>proxied</p>`)
+	}))
+	defer proxy.Close()
+
+	tests := []struct {
+		name    string
+		proxy   string
+		want    []string
+		wantErr bool
+	}{
+		{
+			name:  "Test requests go through the proxy",
+			proxy: proxy.URL,
+			want:  []string{"http://example.invalid/page"},
+		},
+		{
+			name:    "Test unsupported proxy scheme",
+			proxy:   "ftp://" + proxy.Listener.Addr().String(),
+			want:    []string{},
+			wantErr: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			proxied = []string{}
+
+			c := New("http://example.invalid", t.TempDir())
+			c.Proxy = tt.proxy
+			client, err := c.newClient()
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("newClient() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if err == nil {
+				c.client = client
+				if _, err := c.download(context.Background(), "http://example.invalid/page"); err != nil {
+					t.Fatalf("download() error = %v", err)
+				}
+			}
+
+			if !reflect.DeepEqual(proxied, tt.want) {
+				t.Errorf("proxied = %v, want %v", proxied, tt.want)
+			}
+		})
+	}
+}
+
+func TestCrawler_downloadInsecure(t *testing.T) {
+	server := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprint(w, `<p>self-signed</p>`)
+	}))
+	defer server.Close()
+
+	tests := []struct {
+		name     string
+		insecure bool
+		wantErr  bool
+	}{
+		{
+			name:    "Test self-signed certificate is rejected",
+			wantErr: true,
+		},
+		{
+			name:     "Test self-signed certificate is accepted when insecure",
+			insecure: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			c := New(server.URL, t.TempDir())
+			c.Insecure = tt.insecure
+			c.Retries = 0
+			client, err := c.newClient()
+			if err != nil {
+				t.Fatal(err)
+			}
+			c.client = client
+
+			if _, err := c.download(context.Background(), server.URL); (err != nil) != tt.wantErr {
+				t.Errorf("download() error = %v, wantErr %v", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestCrawler_downloadHTTP2(t *testing.T) {
+	server := httptest.NewUnstartedServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprintf(w, `<p>%v</p>`, r.Proto)
+	}))
+	server.EnableHTTP2 = true
+	server.StartTLS()
+	defer server.Close()
+
+	tests := []struct {
+		name  string
+		http2 bool
+		want  string
+	}{
+		{
+			name:  "Test HTTP/2 is negotiated",
+			http2: true,
+			want:  "<p>HTTP/2.0</p>",
+		},
+		{
+			name: "Test HTTP/1.1 is forced",
+			want: "<p>HTTP/1.1</p>",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			c := New(server.URL, t.TempDir())
+			c.Insecure = true
+			c.HTTP2 = tt.http2
+			client, err := c.newClient()
+			if err != nil {
+				t.Fatal(err)
+			}
+			c.client = client
+
+			resp, err := c.download(context.Background(), server.URL)
+			if err != nil {
+				t.Fatalf("download() error = %v", err)
+			}
+			if string(resp.body) != tt.want {
+				t.Errorf("download() = %s, want %v", resp.body, tt.want)
+			}
+		})
+	}
+}
+
+func TestCrawler_extractUrls(t *testing.T) {
+	type args struct {
+		target            string
+		page              string
+		includeSubdomains bool
+		include           []string
+		exclude           []string
+		excludePaths      []string
+		keepQuery         bool
+		ignoreMetaRobots  bool
+		lowerPaths        bool
+		caseInsensitive   bool
+		scope             string
+		extractors        []ExtractorFunc
+		maxLinks          int
+	}
+	tests := []struct {
+		name string
+		args args
+		want []string
+	}{
+		{
+			name: "Test anchor-only links are skipped",
+			args: args{
+				target: "https://example.com",
+				page:   `<a href="#">top</a><a href="#section">section</a><a href="/docs">docs</a>`,
+			},
+			want: []string{"https://example.com/docs"},
+		},
+		{
+			name: "Test bare-slash links are skipped",
+			args: args{
+				target: "https://example.com",
+				page:   `<a href="/">home</a><a href="/blog">blog</a>`,
+			},
+			want: []string{"https://example.com/blog"},
+		},
+		{
+			name: "Test parent-relative links are resolved",
+			args: args{
+				target: "https://example.com/docs/guide/",
+				page:   `<a href="../guide/intro">intro</a><a href="../foo">foo</a>`,
+			},
+			want: []string{"https://example.com/docs/guide/intro"},
+		},
+		{
+			name: "Test page-relative links are resolved",
+			args: args{
+				target: "https://example.com/docs/",
+				page:   `<a href="sub/page.html">page</a><a href="./other">other</a>`,
+			},
+			want: []string{"https://example.com/docs/sub/page.html", "https://example.com/docs/other"},
+		},
+		{
+			name: "Test protocol-relative links are resolved",
+			args: args{
+				target: "https://example.com/docs",
+				page:   `<a href="//example.com/docs/a">a</a><a href="//other.com/docs/b">b</a>`,
+			},
+			want: []string{"https://example.com/docs/a"},
+		},
+		{
+			name: "Test base href is used for relative links",
+			args: args{
+				target: "https://example.com/docs",
+				page:   `<head><base href="/docs/"></head><a href="page.html">page</a>`,
+			},
+			want: []string{"https://example.com/docs/page.html"},
+		},
+		{
+			name: "Test subdomains are skipped by default",
+			args: args{
+				target: "https://example.com/docs",
+				page:   `<a href="https://blog.example.com/post">post</a><a href="/docs/a">a</a>`,
+			},
+			want: []string{"https://example.com/docs/a"},
+		},
+		{
+			name: "Test subdomains are included",
+			args: args{
+				target:            "https://example.com/docs",
+				page:              `<a href="https://blog.example.com/post">post</a><a href="https://evilexample.com/docs/a">evil</a>`,
+				includeSubdomains: true,
+			},
+			want: []string{"https://blog.example.com/post"},
+		},
+		{
+			name: "Test include and exclude patterns",
+			args: args{
+				target:  "https://example.com",
+				page:    `<a href="/docs/a">a</a><a href="/docs/api/b">b</a><a href="/blog">blog</a>`,
+				include: []string{`/docs/`},
+				exclude: []string{`/api/`},
+			},
+			want: []string{"https://example.com/docs/a"},
+		},
+		{
+			name: "Test excluded paths",
+			args: args{
+				target:       "https://example.com",
+				page:         `<a href="/docs/a">a</a><a href="/drafts/b">b</a><a href="/docs/c.pdf">pdf</a><a href="/docs/private/d/e">e</a><a href="/blog">blog</a>`,
+				excludePaths: []string{"/drafts", "*.pdf", "/*/private"},
+			},
+			want: []string{"https://example.com/docs/a", "https://example.com/blog"},
+		},
+		{
+			name: "Test query strings are dropped by default",
+			args: args{
+				target: "https://example.com/search",
+				page:   `<a href="/search/x?q=a">a</a><a href="/search/x?q=b">b</a>`,
+			},
+			want: []string{"https://example.com/search/x"},
+		},
+		{
+			name: "Test query strings are kept and normalized",
+			args: args{
+				target:    "https://example.com/search?q=a",
+				page:      `<a href="?q=b">b</a><a href="?q=a">self</a><a href="/search?b=2&a=1">c</a><a href="/search?a=1&b=2">d</a>`,
+				keepQuery: true,
+			},
+			want: []string{"https://example.com/search?q=b", "https://example.com/search?a=1&b=2"},
+		},
+		{
+			name: "Test query-only links are new targets when queries are kept",
+			args: args{
+				target:    "https://example.com/docs/list?page=1",
+				page:      `<a href="?page=2">2</a><a href="?page=1">1</a><a href="?page=3#results">3</a><a href=" ?page=4 ">4</a><a href="?">none</a>`,
+				keepQuery: true,
+			},
+			want: []string{"https://example.com/docs/list?page=2", "https://example.com/docs/list?page=3", "https://example.com/docs/list?page=4", "https://example.com/docs/list"},
+		},
+		{
+			name: "Test query-only links are the page itself when queries are dropped",
+			args: args{
+				target: "https://example.com/docs/list?page=1",
+				page:   `<a href="?page=2">2</a><a href="?sort=asc">sorted</a>`,
+			},
+			want: []string{},
+		},
+		{
+			name: "Test query-only links keep the trailing slash of the page",
+			args: args{
+				target:    "https://example.com/docs/",
+				page:      `<a href="?page=2">2</a>`,
+				keepQuery: true,
+			},
+			want: []string{"https://example.com/docs/?page=2"},
+		},
+		{
+			name: "Test fragment-only and empty links are the page itself",
+			args: args{
+				target:    "https://example.com/docs?page=1",
+				page:      "<a href=\"#section\">section</a><a href=\"\n #top\">top</a><a href=\"\">self</a><a href=\"?page=1#top\">top</a>",
+				keepQuery: true,
+			},
+			want: []string{},
+		},
+		{
+			name: "Test query-only links resolve against base href",
+			args: args{
+				target:    "https://example.com/docs",
+				page:      `<base href="/docs/archive/"><a href="?page=2">2</a>`,
+				keepQuery: true,
+			},
+			want: []string{"https://example.com/docs/archive/?page=2"},
+		},
+		{
+			name: "Test links past the per-page cap are dropped",
+			args: args{
+				target:   "https://example.com",
+				page:     `<a href="/a">a</a><a href="/a">again</a><a href="mailto:x@example.com">mail</a><a href="/b">b</a><a href="/c">c</a>`,
+				maxLinks: 2,
+			},
+			want: []string{"https://example.com/a", "https://example.com/b"},
+		},
+		{
+			name: "Test rel nofollow links are skipped",
+			args: args{
+				target: "https://example.com",
+				page:   `<a href="/a" rel="nofollow">a</a><a href="/b" rel="Noopener NOFOLLOW">b</a><a href="/c">c</a>`,
+			},
+			want: []string{"https://example.com/c"},
+		},
+		{
+			name: "Test rel nofollow links are followed when meta robots are ignored",
+			args: args{
+				target:           "https://example.com",
+				page:             `<a href="/a" rel="nofollow">a</a><a href="/c">c</a>`,
+				ignoreMetaRobots: true,
+			},
+			want: []string{"https://example.com/a", "https://example.com/c"},
+		},
+		{
+			name: "Test image map areas are followed",
+			args: args{
+				target: "https://example.com/docs",
+				page:   `<p><img src="/docs/map.png" usemap="#nav"></p><map name="nav"><area shape="rect" coords="0,0,10,10" href="/docs/a"><area shape="rect" coords="10,0,20,10" href="/docs/b" rel="nofollow"><area shape="default" nohref></map>`,
+			},
+			want: []string{"https://example.com/docs/a"},
+		},
+		{
+			name: "Test next and prev links are followed",
+			args: args{
+				target: "https://example.com/docs",
+				page:   `<link rel="prev" href="/docs/page/1"><link rel="Next" href="/docs/page/3"><link rel="stylesheet" href="/docs/style.css"><link rel="canonical" href="/docs/page/2"><p>page 2</p>`,
+			},
+			want: []string{"https://example.com/docs/page/1", "https://example.com/docs/page/3"},
+		},
+		{
+			name: "Test registered extractors add links",
+			args: args{
+				target: "https://example.com/docs",
+				page:   `<a href="/docs/a">a</a><div data-href="/docs/b">b</div><iframe src="/docs/c"></iframe><div data-href="/blog">out of scope</div>`,
+				extractors: []ExtractorFunc{
+					func(n *html.Node, _ *url.URL) []string {
+						links := []string{}
+						for _, a := range n.Attr {
+							if a.Key == "data-href" || (n.Data == "iframe" && a.Key == "src") {
+								links = append(links, a.Val)
+							}
+						}
+						return links
+					},
+				},
+			},
+			want: []string{"https://example.com/docs/a", "https://example.com/docs/b", "https://example.com/docs/c"},
+		},
+		{
+			name: "Test path scope keeps children of the page",
+			args: args{
+				target: "https://example.com/docs",
+				page:   `<a href="/docs/a">a</a><a href="/blog">blog</a><a href="https://blog.example.com/post">post</a>`,
+				scope:  ScopePath,
+			},
+			want: []string{"https://example.com/docs/a"},
+		},
+		{
+			name: "Test host scope allows any path on the host",
+			args: args{
+				target: "https://example.com/docs",
+				page:   `<a href="/docs/a">a</a><a href="/blog">blog</a><a href="https://blog.example.com/post">post</a>`,
+				scope:  ScopeHost,
+			},
+			want: []string{"https://example.com/docs/a", "https://example.com/blog"},
+		},
+		{
+			name: "Test domain scope allows subdomains",
+			args: args{
+				target: "https://example.com/docs",
+				page:   `<a href="/blog">blog</a><a href="https://blog.example.com/post">post</a><a href="https://other.com/docs/a">other</a>`,
+				scope:  ScopeDomain,
+			},
+			want: []string{"https://example.com/blog", "https://blog.example.com/post"},
+		},
+		{
+			name: "Test non-http links are skipped",
+			args: args{
+				target: "https://example.com/docs",
+				page:   `<a href="mailto:a@b.com">mail</a><a href="tel:+123">call</a><a href="javascript:void(0)">js</a><a href="ftp://example.com/docs/file">ftp</a><a href="/docs/a">a</a>`,
+			},
+			want: []string{"https://example.com/docs/a"},
+		},
+		{
+			name: "Test equivalent forms of a url are deduplicated",
+			args: args{
+				target: "https://example.com/docs",
+				page:   `<a href="/docs/a">a</a><a href="/docs/a/">slash</a><a href="https://EXAMPLE.com:443/docs/a#x">host</a><a href="/docs/b/../a">dots</a>`,
+			},
+			want: []string{"https://example.com/docs/a"},
+		},
+		{
+			name: "Test paths differing in case are deduplicated when lowercased",
+			args: args{
+				target:     "https://example.com/docs",
+				page:       `<a href="/docs/Page">a</a><a href="/Docs/page">b</a>`,
+				lowerPaths: true,
+			},
+			want: []string{"https://example.com/docs/page"},
+		},
+		{
+			name: "Test child differing in case is out of scope by default",
+			args: args{
+				target: "https://example.com/docs",
+				page:   `<a href="/Docs/page">page</a>`,
+			},
+			want: []string{},
+		},
+		{
+			name: "Test child differing in case is in scope with case-insensitive paths",
+			args: args{
+				target:          "https://example.com/docs",
+				page:            `<a href="/Docs/page">page</a>`,
+				caseInsensitive: true,
+			},
+			want: []string{"https://example.com/Docs/page"},
+		},
+		{
+			name: "Test paths differing in case are deduplicated with case-insensitive paths",
+			args: args{
+				target:          "https://example.com/docs",
+				page:            `<a href="/docs/Page">a</a><a href="/Docs/page">b</a><a href="/DOCS">self</a>`,
+				caseInsensitive: true,
+			},
+			want: []string{"https://example.com/docs/Page"},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			doc, err := parseHTML([]byte(tt.args.page))
+			if err != nil {
+				t.Fatalf("parseHTML() error = %v", err)
+			}
+
+			parsedURL, err := url.Parse(tt.args.target)
+			if err != nil {
+				t.Fatalf("url.Parse() error = %v", err)
+			}
+
+			c := New(tt.args.target, t.TempDir())
+			c.IncludeSubdomains = tt.args.includeSubdomains
+			c.KeepQuery = tt.args.keepQuery
+			c.IgnoreMetaRobots = tt.args.ignoreMetaRobots
+			c.LowercasePaths = tt.args.lowerPaths
+			c.CaseInsensitivePaths = tt.args.caseInsensitive
+			c.Extractors = tt.args.extractors
+			c.MaxLinksPerPage = tt.args.maxLinks
+			if tt.args.scope != "" {
+				c.Scope = tt.args.scope
+			}
+			for _, p := range tt.args.include {
+				c.Include = append(c.Include, regexp.MustCompile(p))
+			}
+			for _, p := range tt.args.exclude {
+				c.Exclude = append(c.Exclude, regexp.MustCompile(p))
+			}
+			c.ExcludePaths = tt.args.excludePaths
+			got, _, err := c.extractUrls(doc, parsedURL)
+			if err != nil {
+				t.Fatalf("extractUrls() error = %v", err)
+			}
+
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("extractUrls() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestCrawler_RunCaseInsensitivePaths(t *testing.T) {
+	var mutex sync.Mutex
+	requests := []string{}
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		mutex.Lock()
+		requests = append(requests, r.URL.Path)
+		mutex.Unlock()
+
+		// a server ignoring the case of paths
+		switch strings.ToLower(r.URL.Path) {
+		case "/docs":
+			fmt.Fprint(w, `<a href="/Docs/page">page</a><a href="/docs/PAGE">again</a>`)
+		case "/docs/page":
+			fmt.Fprint(w, `<p>page</p>`)
+		default:
+			http.NotFound(w, r)
+		}
+	}))
+	defer server.Close()
+
+	c := New(server.URL+"/docs", t.TempDir())
+	c.IgnoreRobots = true
+	c.CaseInsensitivePaths = true
+	if err := c.Run(context.Background()); err != nil {
+		t.Fatalf("Run() error = %v", err)
+	}
+
+	got := []string{}
+	for _, rec := range c.Records() {
+		got = append(got, strings.TrimPrefix(rec.URL, server.URL))
+	}
+	want := []string{"/Docs/page", "/docs"}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("Records() = %v, want %v", got, want)
+	}
+	if want := []string{"/docs", "/Docs/page"}; !reflect.DeepEqual(requests, want) {
+		t.Errorf("requests = %v, want %v", requests, want)
+	}
+}
+
+func TestCrawler_localPath(t *testing.T) {
+	type args struct {
+		target    string
+		keepQuery bool
+		indexName string
+		layout    string
+		pathFor   func(string) string
+	}
+	tests := []struct {
+		name     string
+		args     args
+		wantDir  string
+		wantName string
+	}{
+		{
+			name:     "Test target root",
+			args:     args{target: "https://example.com"},
+			wantDir:  "data",
+			wantName: "index",
+		},
+		{
+			name:     "Test nested page",
+			args:     args{target: "https://example.com/docs/page"},
+			wantDir:  filepath.Join("data", "docs", "page"),
+			wantName: "page",
+		},
+		{
+			name:     "Test query is ignored by default",
+			args:     args{target: "https://example.com/search?q=a"},
+			wantDir:  filepath.Join("data", "search"),
+			wantName: "search",
+		},
+		{
+			name:     "Test query is sanitized into the name",
+			args:     args{target: "https://example.com/search?q=a/b&p=1", keepQuery: true},
+			wantDir:  filepath.Join("data", "search"),
+			wantName: "search_p=1&q=a%2Fb",
+		},
+		{
+			name:     "Test root with a slash",
+			args:     args{target: "https://example.com/"},
+			wantDir:  "data",
+			wantName: "index",
+		},
+		{
+			name:     "Test directory url is its index",
+			args:     args{target: "https://example.com/docs/"},
+			wantDir:  filepath.Join("data", "docs"),
+			wantName: "index",
+		},
+		{
+			name:     "Test custom index name",
+			args:     args{target: "https://example.com/docs/", indexName: "default.htm"},
+			wantDir:  filepath.Join("data", "docs"),
+			wantName: "default",
+		},
+		{
+			name:     "Test empty and dot segments are dropped",
+			args:     args{target: "https://example.com/a//./b/"},
+			wantDir:  filepath.Join("data", "a", "b"),
+			wantName: "index",
+		},
+		{
+			name:     "Test illegal characters and reserved names are sanitized",
+			args:     args{target: "https://example.com/x%3Ay/con.txt"},
+			wantDir:  filepath.Join("data", "x_y", "_con.txt"),
+			wantName: "_con.txt",
+		},
+		{
+			name:     "Test hostname layout puts the target in its host dir",
+			args:     args{target: "https://example.com/docs/page", layout: LayoutHostname},
+			wantDir:  filepath.Join("data", "example.com", "docs", "page"),
+			wantName: "page",
+		},
+		{
+			name:     "Test flat layout names pages by their url hash",
+			args:     args{target: "https://example.com/docs/page/", layout: LayoutFlat},
+			wantDir:  "data",
+			wantName: "024e1bb310ba04b4d5add29226216d10",
+		},
+		{
+			name: "Test custom path stays in the output dir",
+			args: args{
+				target:  "https://example.com/docs/page",
+				pathFor: func(u string) string { return "../pages/" + path.Base(u) + ".html" },
+			},
+			wantDir:  filepath.Join("data", "pages"),
+			wantName: "page",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			u, err := url.Parse(tt.args.target)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			c := New(tt.args.target, "data")
+			c.KeepQuery = tt.args.keepQuery
+			if tt.args.indexName != "" {
+				c.IndexName = tt.args.indexName
+			}
+			if tt.args.layout != "" {
+				c.Layout = tt.args.layout
+			}
+			c.PathFor = tt.args.pathFor
+
+			gotDir, gotName := c.localPath(u)
+			if gotDir != tt.wantDir || gotName != tt.wantName {
+				t.Errorf("localPath() = %v, %v, want %v, %v", gotDir, gotName, tt.wantDir, tt.wantName)
+			}
+		})
+	}
+}
+
+func Test_normalizeURL(t *testing.T) {
+	type args struct {
+		raw       string
+		lowerPath bool
+	}
+	tests := []struct {
+		name string
+		args args
+		want string
+	}{
+		{
+			name: "Test host is lowercased",
+			args: args{raw: "https://Example.COM/docs"},
+			want: "https://example.com/docs",
+		},
+		{
+			name: "Test default http port is dropped",
+			args: args{raw: "http://example.com:80/docs"},
+			want: "http://example.com/docs",
+		},
+		{
+			name: "Test default https port is dropped",
+			args: args{raw: "https://example.com:443/docs"},
+			want: "https://example.com/docs",
+		},
+		{
+			name: "Test other ports are kept",
+			args: args{raw: "https://example.com:8443/docs"},
+			want: "https://example.com:8443/docs",
+		},
+		{
+			name: "Test fragment is stripped",
+			args: args{raw: "https://example.com/docs#intro"},
+			want: "https://example.com/docs",
+		},
+		{
+			name: "Test trailing slash is stripped",
+			args: args{raw: "https://example.com/docs/"},
+			want: "https://example.com/docs",
+		},
+		{
+			name: "Test root is empty",
+			args: args{raw: "https://example.com/"},
+			want: "https://example.com",
+		},
+		{
+			name: "Test dot segments are resolved",
+			args: args{raw: "https://example.com/docs/./a/../b"},
+			want: "https://example.com/docs/b",
+		},
+		{
+			name: "Test path case is kept by default",
+			args: args{raw: "https://example.com/Docs"},
+			want: "https://example.com/Docs",
+		},
+		{
+			name: "Test path is lowercased when asked",
+			args: args{raw: "https://example.com/Docs", lowerPath: true},
+			want: "https://example.com/docs",
+		},
+		{
+			name: "Test query is kept",
+			args: args{raw: "https://example.com/search?q=a"},
+			want: "https://example.com/search?q=a",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			u, err := url.Parse(tt.args.raw)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			if got := normalizeURL(u, tt.args.lowerPath).String(); got != tt.want {
+				t.Errorf("normalizeURL(%v) = %v, want %v", tt.args.raw, got, tt.want)
+			}
+		})
+	}
+}
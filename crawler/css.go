@@ -0,0 +1,64 @@
+package crawler
+
+import (
+	"mime"
+	"net/url"
+	"path"
+	"regexp"
+)
+
+var (
+	// cssURL matches url(...) with the reference quoted or not.
+	cssURL = regexp.MustCompile(`url\(\s*(?:"([^"]*)"|'([^']*)'|([^)"'\s]*))\s*\)`)
+
+	// cssImport matches @import rules written with a bare string; those
+	// using url() are found by cssURL.
+	cssImport = regexp.MustCompile(`@import\s+(?:"([^"]*)"|'([^']*)')`)
+
+	cssComment = regexp.MustCompile(`(?s)/\*.*?\*/`)
+)
+
+// extractCSS returns the absolute urls of the images, fonts and stylesheets
+// css loads through url() and @import. References are relative to base,
+// which is the stylesheet's own url for a stylesheet file and the page's
+// base for inline styles.
+func (c *Crawler) extractCSS(css string, base, pageURL *url.URL) []string {
+	css = cssComment.ReplaceAllString(css, "")
+
+	assets := []string{}
+	found := map[string]struct{}{}
+	for _, r := range []*regexp.Regexp{cssImport, cssURL} {
+		for _, m := range r.FindAllStringSubmatch(css, -1) {
+			ref := ""
+			for _, group := range m[1:] {
+				if group != "" {
+					ref = group
+				}
+			}
+			if ref == "" {
+				continue
+			}
+
+			u, ok := c.assetURL(ref, base, pageURL)
+			if !ok {
+				continue
+			}
+			if _, ok := found[u]; !ok {
+				found[u] = struct{}{}
+				assets = append(assets, u)
+			}
+		}
+	}
+
+	return assets
+}
+
+// isCSS reports whether the asset at u served as contentType is a
+// stylesheet.
+func isCSS(u *url.URL, contentType string) bool {
+	if t, _, err := mime.ParseMediaType(contentType); err == nil {
+		return t == "text/css"
+	}
+
+	return path.Ext(u.Path) == ".css"
+}
@@ -0,0 +1,142 @@
+package crawler
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"io"
+	"os"
+	"path/filepath"
+	"sync"
+)
+
+// contentDir is the directory of the output dir where DedupContent keeps a
+// single copy of every distinct body, named after its SHA-256.
+const contentDir = ".content"
+
+// manifestFile lists the hash of every url's body, in contentDir.
+const manifestFile = "manifest.json"
+
+// contentStore serializes the checks for a stored copy, so two identical
+// bodies saved at once are still written only once.
+type contentStore struct {
+	mutex sync.Mutex
+}
+
+// contentHash returns the hex SHA-256 of data.
+func contentHash(data []byte) string {
+	sum := sha256.Sum256(data)
+	return hex.EncodeToString(sum[:])
+}
+
+// saveBody saves a downloaded body as fileName and returns its hash. With
+// DedupContent the body is stored once under its hash and fileName links to
+// that copy.
+func (c *Crawler) saveBody(filePath, fileName string, data []byte, meta ObjectMeta) (string, error) {
+	hash := contentHash(data)
+	if !c.DedupContent || c.DryRun || !c.onDisk() {
+		return hash, c.save(filePath, fileName, data, meta)
+	}
+
+	object, _, err := c.storeContent(hash, int64(len(data)), func(object string) error {
+		defer c.openFile()()
+		return writeFile(object, data)
+	})
+	if err != nil {
+		return hash, err
+	}
+
+	return hash, linkFile(object, filepath.Join(filePath, fileName))
+}
+
+// storeContent returns the path of the stored copy of the body hashed as
+// hash, calling write to create it unless it was stored already, by this
+// crawl or a previous one. A body found there counts its size as saved.
+func (c *Crawler) storeContent(hash string, size int64, write func(object string) error) (object string, stored bool, err error) {
+	object = filepath.Join(c.dir, contentDir, hash)
+
+	c.content.mutex.Lock()
+	defer c.content.mutex.Unlock()
+
+	if _, err := os.Stat(object); err == nil {
+		c.stats.deduped.Add(size)
+		return object, true, nil
+	}
+	if err := os.MkdirAll(filepath.Dir(object), os.ModePerm); err != nil {
+		return object, false, err
+	}
+
+	return object, false, write(object)
+}
+
+// linkFile makes fileName a hard link to object, replacing any previous
+// copy. Where links aren't supported it is a copy of object instead.
+func linkFile(object, fileName string) error {
+	tmp, err := createTemp(fileName)
+	if err != nil {
+		return err
+	}
+
+	// only the name is needed for the link
+	tmp.Close()
+	os.Remove(tmp.Name())
+	if err := os.Link(object, tmp.Name()); err == nil {
+		if err := os.Rename(tmp.Name(), fileName); err != nil {
+			os.Remove(tmp.Name())
+			return err
+		}
+		return nil
+	}
+
+	return copyFile(object, fileName)
+}
+
+// copyFile saves a copy of src as fileName.
+func copyFile(src, fileName string) error {
+	in, err := os.Open(src)
+	if err != nil {
+		return err
+	}
+	defer in.Close()
+
+	tmp, err := createTemp(fileName)
+	if err != nil {
+		return err
+	}
+	if _, err := io.Copy(tmp, in); err != nil {
+		tmp.discard()
+		return err
+	}
+
+	return tmp.commit()
+}
+
+// writeManifest adds the hash of every body saved in this crawl to the
+// manifest, keeping the urls a previous run listed.
+func (c *Crawler) writeManifest() error {
+	fileName := filepath.Join(c.dir, contentDir, manifestFile)
+
+	manifest := map[string]string{}
+	if data, err := os.ReadFile(fileName); err == nil {
+		if err := json.Unmarshal(data, &manifest); err != nil {
+			c.Logger.Warn("ignoring the unreadable content manifest", "path", fileName, "err", err)
+		}
+	}
+
+	for _, rec := range c.Records() {
+		if rec.ContentHash == "" || rec.Path == "" {
+			continue
+		}
+		manifest[rec.URL] = rec.ContentHash
+		if rec.FinalURL != "" {
+			manifest[rec.FinalURL] = rec.ContentHash
+		}
+	}
+
+	data, err := json.MarshalIndent(manifest, "", "  ")
+	if err != nil {
+		return err
+	}
+
+	return writeFile(fileName, data)
+}
@@ -0,0 +1,107 @@
+package crawler
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestCrawler_RunDedupContent(t *testing.T) {
+	page := `<html><body><p>same</p></body></html>`
+	image := "\x89PNG same image"
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/docs":
+			fmt.Fprint(w, `<a href="/docs/a">a</a><a href="/docs/b">b</a><img src="/img/1.png"><img src="/img/2.png">`)
+		case "/docs/a", "/docs/b":
+			fmt.Fprint(w, page)
+		case "/img/1.png", "/img/2.png":
+			w.Header().Set("Content-Type", "image/png")
+			fmt.Fprint(w, image)
+		default:
+			http.NotFound(w, r)
+		}
+	}))
+	defer server.Close()
+
+	tests := []struct {
+		name        string
+		dedup       bool
+		wantObjects int
+		wantDeduped int64
+	}{
+		{
+			name: "Test every page is written by default",
+		},
+		{
+			name:        "Test identical bodies are stored once",
+			dedup:       true,
+			wantObjects: 3,
+			wantDeduped: int64(len(page) + len(image)),
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			dir := t.TempDir()
+			c := New(server.URL+"/docs", dir)
+			c.IgnoreRobots = true
+			c.Assets = true
+			c.DedupContent = tt.dedup
+			if err := c.Run(context.Background()); err != nil {
+				t.Fatalf("Run() error = %v", err)
+			}
+
+			if got := c.Stats().DedupedBytes; got != tt.wantDeduped {
+				t.Errorf("Stats().DedupedBytes = %v, want %v", got, tt.wantDeduped)
+			}
+
+			for _, pair := range [][2]string{
+				{"docs/a/a.html", "docs/b/b.html"},
+				{"img/1.png", "img/2.png"},
+			} {
+				a, err := os.Stat(filepath.Join(dir, pair[0]))
+				if err != nil {
+					t.Fatal(err)
+				}
+				b, err := os.Stat(filepath.Join(dir, pair[1]))
+				if err != nil {
+					t.Fatal(err)
+				}
+				if os.SameFile(a, b) != tt.dedup {
+					t.Errorf("%v and %v are the same file: %v, want %v", pair[0], pair[1], !tt.dedup, tt.dedup)
+				}
+			}
+
+			entries, _ := os.ReadDir(filepath.Join(dir, contentDir))
+			objects := 0
+			for _, e := range entries {
+				if e.Name() != manifestFile {
+					objects++
+				}
+			}
+			if objects != tt.wantObjects {
+				t.Errorf("stored %v bodies, want %v", objects, tt.wantObjects)
+			}
+			if !tt.dedup {
+				return
+			}
+
+			data, err := os.ReadFile(filepath.Join(dir, contentDir, manifestFile))
+			if err != nil {
+				t.Fatal(err)
+			}
+			manifest := map[string]string{}
+			if err := json.Unmarshal(data, &manifest); err != nil {
+				t.Fatal(err)
+			}
+			if len(manifest) != 5 || manifest[server.URL+"/docs/a"] != contentHash([]byte(page)) || manifest[server.URL+"/img/1.png"] != manifest[server.URL+"/img/2.png"] {
+				t.Errorf("manifest = %v, want the hash of all 5 urls", manifest)
+			}
+		})
+	}
+}
@@ -0,0 +1,84 @@
+package crawler
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"sort"
+)
+
+// Diff lists what changed between two crawls of a site, each list sorted.
+type Diff struct {
+	// Added holds the urls only the new crawl found, Removed the ones only
+	// the previous one did.
+	Added   []string `json:"added"`
+	Removed []string `json:"removed"`
+
+	// Changed holds the urls whose content hash differs. One with no hash
+	// in either crawl, like a failed page, is not counted as changed.
+	Changed []string `json:"changed"`
+}
+
+// DiffRecords compares the records of a crawl to those of a previous one.
+func DiffRecords(previous, current []Record) Diff {
+	before := make(map[string]string, len(previous))
+	for _, rec := range previous {
+		before[rec.URL] = rec.ContentHash
+	}
+
+	d := Diff{Added: []string{}, Removed: []string{}, Changed: []string{}}
+	now := make(map[string]bool, len(current))
+	for _, rec := range current {
+		now[rec.URL] = true
+		hash, ok := before[rec.URL]
+		switch {
+		case !ok:
+			d.Added = append(d.Added, rec.URL)
+		case hash != "" && rec.ContentHash != "" && hash != rec.ContentHash:
+			d.Changed = append(d.Changed, rec.URL)
+		}
+	}
+	for u := range before {
+		if !now[u] {
+			d.Removed = append(d.Removed, u)
+		}
+	}
+
+	sort.Strings(d.Added)
+	sort.Strings(d.Removed)
+	sort.Strings(d.Changed)
+
+	return d
+}
+
+// Diff compares the records collected so far to those of a previous crawl.
+func (c *Crawler) Diff(previous []Record) Diff {
+	return DiffRecords(previous, c.Records())
+}
+
+// WriteDiff writes d to fileName as JSON.
+func WriteDiff(fileName string, d Diff) error {
+	data, err := json.MarshalIndent(d, "", "  ")
+	if err != nil {
+		return err
+	}
+
+	return os.WriteFile(fileName, data, 0644)
+}
+
+// Print writes a human readable summary of d to w, with every url.
+func (d Diff) Print(w io.Writer) {
+	fmt.Fprintf(w, "added:      %v\n", len(d.Added))
+	for _, u := range d.Added {
+		fmt.Fprintf(w, "  + %v\n", u)
+	}
+	fmt.Fprintf(w, "removed:    %v\n", len(d.Removed))
+	for _, u := range d.Removed {
+		fmt.Fprintf(w, "  - %v\n", u)
+	}
+	fmt.Fprintf(w, "changed:    %v\n", len(d.Changed))
+	for _, u := range d.Changed {
+		fmt.Fprintf(w, "  ~ %v\n", u)
+	}
+}
@@ -0,0 +1,113 @@
+package crawler
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"sync/atomic"
+	"testing"
+)
+
+func TestDiffRecords(t *testing.T) {
+	tests := []struct {
+		name     string
+		previous []Record
+		current  []Record
+		want     Diff
+	}{
+		{
+			name:     "Test same crawl",
+			previous: []Record{{URL: "/a", ContentHash: "1"}, {URL: "/b", ContentHash: "2"}},
+			current:  []Record{{URL: "/a", ContentHash: "1"}, {URL: "/b", ContentHash: "2"}},
+			want:     Diff{Added: []string{}, Removed: []string{}, Changed: []string{}},
+		},
+		{
+			name:     "Test added, removed and changed urls",
+			previous: []Record{{URL: "/a", ContentHash: "1"}, {URL: "/b", ContentHash: "2"}},
+			current:  []Record{{URL: "/a", ContentHash: "3"}, {URL: "/d"}, {URL: "/c", ContentHash: "4"}},
+			want:     Diff{Added: []string{"/c", "/d"}, Removed: []string{"/b"}, Changed: []string{"/a"}},
+		},
+		{
+			name:     "Test url without a hash is not changed",
+			previous: []Record{{URL: "/a", ContentHash: "1"}, {URL: "/b"}},
+			current:  []Record{{URL: "/a", Error: "404 Not Found"}, {URL: "/b", ContentHash: "2"}},
+			want:     Diff{Added: []string{}, Removed: []string{}, Changed: []string{}},
+		},
+		{
+			name:    "Test first crawl",
+			current: []Record{{URL: "/a", ContentHash: "1"}},
+			want:    Diff{Added: []string{"/a"}, Removed: []string{}, Changed: []string{}},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := DiffRecords(tt.previous, tt.current); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("DiffRecords() = %+v, want %+v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestCrawler_Diff(t *testing.T) {
+	var run atomic.Int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		second := run.Load() > 0
+		switch {
+		case r.URL.Path == "/docs" && second:
+			fmt.Fprint(w, `<a href="/docs/intro">intro</a><a href="/docs/new">new</a>`)
+		case r.URL.Path == "/docs":
+			fmt.Fprint(w, `<a href="/docs/intro">intro</a><a href="/docs/old">old</a>`)
+		case r.URL.Path == "/docs/intro" && second:
+			fmt.Fprint(w, `<p>intro, updated</p>`)
+		case r.URL.Path == "/docs/intro", r.URL.Path == "/docs/old", r.URL.Path == "/docs/new":
+			fmt.Fprint(w, `<p>intro</p>`)
+		default:
+			http.NotFound(w, r)
+		}
+	}))
+	defer server.Close()
+
+	report := filepath.Join(t.TempDir(), "report.json")
+	c := New(server.URL+"/docs", t.TempDir())
+	c.IgnoreRobots = true
+	if err := c.Run(context.Background()); err != nil {
+		t.Fatalf("Run() error = %v", err)
+	}
+	if err := c.WriteReport(report); err != nil {
+		t.Fatal(err)
+	}
+
+	run.Add(1)
+	c = New(server.URL+"/docs", t.TempDir())
+	c.IgnoreRobots = true
+	if err := c.Run(context.Background()); err != nil {
+		t.Fatalf("Run() error = %v", err)
+	}
+
+	previous, err := ReadReport(report)
+	if err != nil {
+		t.Fatalf("ReadReport() error = %v", err)
+	}
+	got := c.Diff(previous)
+	want := Diff{
+		Added:   []string{server.URL + "/docs/new"},
+		Removed: []string{server.URL + "/docs/old"},
+		Changed: []string{server.URL + "/docs", server.URL + "/docs/intro"},
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Fatalf("Diff() = %+v, want %+v", got, want)
+	}
+
+	var buf bytes.Buffer
+	got.Print(&buf)
+	for _, line := range []string{"added:      1", "  + " + want.Added[0], "removed:    1", "  - " + want.Removed[0], "changed:    2"} {
+		if !strings.Contains(buf.String(), line+"\n") {
+			t.Errorf("Print() = %q, want a line %q", buf.String(), line)
+		}
+	}
+}
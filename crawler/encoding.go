@@ -0,0 +1,63 @@
+package crawler
+
+import (
+	"bufio"
+	"compress/flate"
+	"compress/gzip"
+	"compress/zlib"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+
+	"github.com/andybalholm/brotli"
+)
+
+// acceptEncoding is sent with every page request. Setting it ourselves turns
+// off the transport's transparent gzip support, so decodeBody handles all
+// of them.
+const acceptEncoding = "gzip, deflate, br"
+
+// decodeBody returns a reader over the decompressed body of resp, following
+// its Content-Encoding. Unknown encodings are an error rather than garbage
+// bytes saved to disk.
+func decodeBody(resp *http.Response) (io.Reader, error) {
+	var body io.Reader = resp.Body
+
+	// encodings are listed in the order they were applied
+	encodings := strings.Split(resp.Header.Get("Content-Encoding"), ",")
+	for i := len(encodings) - 1; i >= 0; i-- {
+		switch strings.ToLower(strings.TrimSpace(encodings[i])) {
+		case "", "identity":
+		case "gzip", "x-gzip":
+			r, err := gzip.NewReader(body)
+			if err != nil {
+				return nil, err
+			}
+			body = r
+		case "deflate":
+			body = deflateReader(body)
+		case "br":
+			body = brotli.NewReader(body)
+		default:
+			return nil, fmt.Errorf("unsupported content encoding %q", encodings[i])
+		}
+	}
+
+	return body, nil
+}
+
+// deflateReader reads a "deflate" body. The spec says zlib but some servers
+// send a raw deflate stream, so look at the header before deciding.
+func deflateReader(r io.Reader) io.Reader {
+	br := bufio.NewReader(r)
+
+	header, err := br.Peek(2)
+	if err == nil && header[0]&0x0f == 8 && (uint16(header[0])<<8|uint16(header[1]))%31 == 0 {
+		if zr, err := zlib.NewReader(br); err == nil {
+			return zr
+		}
+	}
+
+	return flate.NewReader(br)
+}
@@ -0,0 +1,110 @@
+package crawler
+
+import (
+	"bytes"
+	"compress/flate"
+	"compress/gzip"
+	"compress/zlib"
+	"context"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"testing"
+
+	"github.com/andybalholm/brotli"
+)
+
+const encodedPage = `<html><body><a href="/docs/a">a</a></body></html>`
+
+func compress(t *testing.T, encoding string, data []byte) []byte {
+	var buf bytes.Buffer
+
+	var w io.WriteCloser
+	switch encoding {
+	case "gzip":
+		w = gzip.NewWriter(&buf)
+	case "deflate":
+		w = zlib.NewWriter(&buf)
+	case "raw-deflate":
+		fw, err := flate.NewWriter(&buf, flate.DefaultCompression)
+		if err != nil {
+			t.Fatal(err)
+		}
+		w = fw
+	case "br":
+		w = brotli.NewWriter(&buf)
+	default:
+		return data
+	}
+
+	if _, err := w.Write(data); err != nil {
+		t.Fatal(err)
+	}
+	if err := w.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	return buf.Bytes()
+}
+
+func TestCrawler_downloadEncoded(t *testing.T) {
+	tests := []struct {
+		name     string
+		encoding string
+		header   string
+		wantErr  bool
+	}{
+		{name: "Test gzip body", encoding: "gzip", header: "gzip"},
+		{name: "Test zlib deflate body", encoding: "deflate", header: "deflate"},
+		{name: "Test raw deflate body", encoding: "raw-deflate", header: "deflate"},
+		{name: "Test brotli body", encoding: "br", header: "br"},
+		{name: "Test identity body", encoding: "identity", header: ""},
+		{name: "Test unknown encoding", encoding: "identity", header: "zstd", wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			body := compress(t, tt.encoding, []byte(encodedPage))
+			server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				if tt.header != "" {
+					w.Header().Set("Content-Encoding", tt.header)
+				}
+				w.Header().Set("Content-Type", "text/html")
+				w.Write(body)
+			}))
+			defer server.Close()
+
+			c := New(server.URL+"/docs", t.TempDir())
+			c.Retries = 0
+			client, err := c.newClient()
+			if err != nil {
+				t.Fatal(err)
+			}
+			c.client = client
+
+			resp, err := c.download(context.Background(), server.URL+"/docs")
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("download() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if tt.wantErr {
+				return
+			}
+
+			if string(resp.body) != encodedPage {
+				t.Fatalf("download() body = %q, want %q", resp.body, encodedPage)
+			}
+
+			doc, err := parseHTML(resp.body)
+			if err != nil {
+				t.Fatalf("parseHTML() error = %v", err)
+			}
+			got, _, err := c.extractUrls(doc, resp.url)
+			if err != nil {
+				t.Fatalf("extractUrls() error = %v", err)
+			}
+			if want := []string{server.URL + "/docs/a"}; !reflect.DeepEqual(got, want) {
+				t.Errorf("extractUrls() = %v, want %v", got, want)
+			}
+		})
+	}
+}
@@ -0,0 +1,62 @@
+package crawler
+
+import (
+	"errors"
+	"fmt"
+	"io/fs"
+	"syscall"
+)
+
+// PageError is a failure limited to a single page. The crawl carries on
+// when one happens.
+type PageError struct {
+	URL string
+	Err error
+}
+
+func (e *PageError) Error() string {
+	return fmt.Sprintf("%v: %v", e.URL, e.Err)
+}
+
+func (e *PageError) Unwrap() error {
+	return e.Err
+}
+
+// PageErrors is returned by Run when the crawl completed but some pages
+// failed.
+type PageErrors []*PageError
+
+func (e PageErrors) Error() string {
+	if len(e) == 1 {
+		return e[0].Error()
+	}
+
+	return fmt.Sprintf("%v pages failed, first: %v", len(e), e[0])
+}
+
+// isFatal reports whether err means the output directory is unusable, in
+// which case every following page would fail the same way.
+func isFatal(err error) bool {
+	return errors.Is(err, fs.ErrPermission) || errors.Is(err, syscall.ENOSPC) || errors.Is(err, syscall.EROFS)
+}
+
+func (c *Crawler) addPageError(err *PageError) {
+	c.emit(Event{Kind: EventError, URL: err.URL, Err: err.Err})
+
+	c.errorsMutex.Lock()
+	defer c.errorsMutex.Unlock()
+
+	c.pageErrors = append(c.pageErrors, err)
+}
+
+// fail stops the whole crawl. Only the first fatal error is kept.
+func (c *Crawler) fail(err error) {
+	c.errorsMutex.Lock()
+	defer c.errorsMutex.Unlock()
+
+	if c.fatal == nil {
+		c.fatal = err
+		c.cancel()
+		c.emit(Event{Kind: EventError, Err: err})
+	}
+}
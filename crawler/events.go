@@ -0,0 +1,112 @@
+package crawler
+
+import (
+	"time"
+)
+
+// eventBuffer is how many events wait for a slow consumer before new ones
+// are dropped.
+const eventBuffer = 1024
+
+// EventKind tells what an Event reports.
+type EventKind int
+
+const (
+	// EventQueued is sent when a page or asset is added to the frontier.
+	// URL, Depth and Asset describe it.
+	EventQueued EventKind = iota
+
+	// EventStarted is sent when the download of URL begins. Retries of the
+	// same download don't send it again.
+	EventStarted
+
+	// EventFinished is sent when the download of URL is over, retries
+	// included. Status is the last status the server answered with, if
+	// any, Bytes the size of the body and Err why it failed, if it did.
+	EventFinished
+
+	// EventError is sent when a page or asset fails, whether downloading,
+	// saving or parsing it, with Err. A fatal error stopping the crawl is
+	// sent with no URL.
+	EventError
+)
+
+func (k EventKind) String() string {
+	switch k {
+	case EventQueued:
+		return "queued"
+	case EventStarted:
+		return "started"
+	case EventFinished:
+		return "finished"
+	case EventError:
+		return "error"
+	default:
+		return "unknown"
+	}
+}
+
+// Event is something that happened during the crawl. The fields set depend
+// on its Kind.
+type Event struct {
+	Kind   EventKind
+	Time   time.Time
+	URL    string
+	Depth  int
+	Asset  bool
+	Status int
+	Bytes  int64
+	Err    error
+}
+
+// Events returns the channel the events of the crawl are sent on. It must be
+// called before Run, which closes the channel when it returns. The channel
+// is buffered, and events are dropped rather than slowing the crawl down
+// when the consumer falls behind.
+func (c *Crawler) Events() <-chan Event {
+	c.eventsMutex.Lock()
+	defer c.eventsMutex.Unlock()
+
+	if c.events == nil {
+		c.events = make(chan Event, eventBuffer)
+	}
+
+	return c.events
+}
+
+// emit sends e to the Events channel, if there is one and it has room.
+func (c *Crawler) emit(e Event) {
+	c.eventsMutex.Lock()
+	defer c.eventsMutex.Unlock()
+
+	if c.events == nil {
+		return
+	}
+
+	e.Time = time.Now()
+	select {
+	case c.events <- e:
+	default:
+	}
+}
+
+// emitFinished sends the EventFinished of the download of url.
+func (c *Crawler) emitFinished(url string, r *response, err error) {
+	e := Event{Kind: EventFinished, URL: url, Err: err}
+	if r != nil {
+		e.Status = r.status
+		e.Bytes = r.size + int64(len(r.body))
+	}
+	c.emit(e)
+}
+
+// closeEvents closes the Events channel once the crawl is over.
+func (c *Crawler) closeEvents() {
+	c.eventsMutex.Lock()
+	defer c.eventsMutex.Unlock()
+
+	if c.events != nil {
+		close(c.events)
+		c.events = nil
+	}
+}
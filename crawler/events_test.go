@@ -0,0 +1,74 @@
+package crawler
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"testing"
+)
+
+func TestCrawler_Events(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/docs":
+			fmt.Fprint(w, `<a href="/docs/a">a</a><a href="/docs/missing">missing</a>`)
+		case "/docs/a":
+			fmt.Fprint(w, `<p>a</p>`)
+		default:
+			http.NotFound(w, r)
+		}
+	}))
+	defer server.Close()
+
+	c := New(server.URL+"/docs", t.TempDir())
+	c.IgnoreRobots = true
+	c.Retries = 0
+	events := c.Events()
+
+	// nothing is read until the crawl is over, which mustn't hold it up
+	c.Run(context.Background())
+
+	kinds := map[string][]EventKind{}
+	status := map[string]int{}
+	for e := range events {
+		kinds[e.URL] = append(kinds[e.URL], e.Kind)
+		if e.Kind == EventFinished {
+			status[e.URL] = e.Status
+		}
+		if e.Time.IsZero() {
+			t.Errorf("event %v of %v has no time", e.Kind, e.URL)
+		}
+	}
+
+	tests := []struct {
+		name       string
+		url        string
+		wantKinds  []EventKind
+		wantStatus int
+	}{
+		{
+			name:       "Test downloaded page",
+			url:        server.URL + "/docs/a",
+			wantKinds:  []EventKind{EventQueued, EventStarted, EventFinished},
+			wantStatus: http.StatusOK,
+		},
+		{
+			name:       "Test failed page",
+			url:        server.URL + "/docs/missing",
+			wantKinds:  []EventKind{EventQueued, EventStarted, EventFinished, EventError},
+			wantStatus: http.StatusNotFound,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := kinds[tt.url]; !reflect.DeepEqual(got, tt.wantKinds) {
+				t.Errorf("events of %v = %v, want %v", tt.url, got, tt.wantKinds)
+			}
+			if got := status[tt.url]; got != tt.wantStatus {
+				t.Errorf("finished with status %v, want %v", got, tt.wantStatus)
+			}
+		})
+	}
+}
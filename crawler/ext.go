@@ -0,0 +1,84 @@
+package crawler
+
+import (
+	"mime"
+	"net/url"
+	"path/filepath"
+	"strings"
+)
+
+// The extensions a Crawler can give saved pages.
+const (
+	// ExtHTML appends .html to every page, e.g. page.php.html.
+	ExtHTML = "html"
+	// ExtAuto replaces the url's extension with one of the Content-Type,
+	// e.g. page.html for a page.php serving text/html.
+	ExtAuto = "auto"
+	// ExtPreserve keeps the url's extension, e.g. page.php, and appends
+	// .html only to urls without one.
+	ExtPreserve = "preserve"
+)
+
+// pageFile returns the name a page saved as fileName, the base name
+// localPath gave u, is written under, following Ext. t is the media type
+// it is served with, if known.
+func (c *Crawler) pageFile(u *url.URL, fileName, t string) string {
+	if c.Ext != ExtAuto && c.Ext != ExtPreserve {
+		return fileName + ".html"
+	}
+
+	urlExt := ""
+	if segments := pathSegments(u.Path); len(segments) > 0 && !strings.HasSuffix(u.Path, "/") {
+		urlExt = filepath.Ext(segments[len(segments)-1])
+	}
+	stem := strings.TrimSuffix(fileName, urlExt)
+
+	if c.Ext == ExtPreserve {
+		if urlExt == "" {
+			return fileName + ".html"
+		}
+		return stem + urlExt
+	}
+
+	return stem + typeExt(t, urlExt)
+}
+
+// savedPageFile is pageFile for a page that may have been saved by an
+// earlier crawl, before it is downloaded again: with ExtAuto, the type is
+// taken from its metadata.
+func (c *Crawler) savedPageFile(u *url.URL, filePath, fileName string) string {
+	t := ""
+	if c.Ext == ExtAuto {
+		if meta := c.readMeta(filePath, fileName); meta != nil && meta.ContentType != "" {
+			t = mediaType(meta.ContentType, nil)
+		}
+	}
+
+	return c.pageFile(u, fileName, t)
+}
+
+// typeExt returns the extension of files of media type t: urlExt when it
+// is one of them, .html for HTML, and .html too when t is unknown.
+func typeExt(t, urlExt string) string {
+	if t == "" {
+		return ".html"
+	}
+
+	exts, err := mime.ExtensionsByType(t)
+	if err != nil || len(exts) == 0 {
+		return ".html"
+	}
+
+	for _, ext := range exts {
+		if strings.EqualFold(ext, urlExt) {
+			return urlExt
+		}
+	}
+	for _, ext := range exts {
+		if ext == ".html" {
+			return ext
+		}
+	}
+
+	return exts[0]
+}
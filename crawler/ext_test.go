@@ -0,0 +1,128 @@
+package crawler
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestCrawler_RunExt(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		// fresh for the second run
+		w.Header().Set("Cache-Control", "max-age=3600")
+		switch r.URL.Path {
+		case "/docs":
+			fmt.Fprint(w, `<a href="/docs/page.php">php</a><a href="/docs/page.htm">htm</a><a href="/docs/about">about</a>`)
+		case "/docs/page.php", "/docs/page.htm", "/docs/about":
+			fmt.Fprint(w, `<p>page</p>`)
+		default:
+			http.NotFound(w, r)
+		}
+	}))
+	defer server.Close()
+
+	tests := []struct {
+		name string
+		ext  string
+		want []string
+	}{
+		{
+			name: "Test html is appended by default",
+			ext:  ExtHTML,
+			want: []string{
+				filepath.Join("docs", "docs.html"),
+				filepath.Join("docs", "page.php", "page.php.html"),
+				filepath.Join("docs", "about", "about.html"),
+				filepath.Join("docs", "page.htm", "page.htm.html"),
+			},
+		},
+		{
+			name: "Test auto uses the extension of the content type",
+			ext:  ExtAuto,
+			want: []string{
+				filepath.Join("docs", "docs.html"),
+				filepath.Join("docs", "page.php", "page.html"),
+				filepath.Join("docs", "about", "about.html"),
+				filepath.Join("docs", "page.htm", "page.htm"),
+			},
+		},
+		{
+			name: "Test preserve keeps the extension of the url",
+			ext:  ExtPreserve,
+			want: []string{
+				filepath.Join("docs", "docs.html"),
+				filepath.Join("docs", "page.php", "page.php"),
+				filepath.Join("docs", "about", "about.html"),
+				filepath.Join("docs", "page.htm", "page.htm"),
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			dir := t.TempDir()
+			for run := 0; run < 2; run++ {
+				c := New(server.URL+"/docs", dir)
+				c.IgnoreRobots = true
+				c.Ext = tt.ext
+				if err := c.Run(context.Background()); err != nil {
+					t.Fatalf("Run() error = %v", err)
+				}
+
+				for _, rec := range c.Records() {
+					if cached := run == 1; rec.Cached != cached {
+						t.Errorf("run %v: %v cached = %v, want %v", run, rec.URL, rec.Cached, cached)
+					}
+				}
+			}
+
+			for _, name := range tt.want {
+				if _, err := os.Stat(filepath.Join(dir, name)); err != nil {
+					t.Errorf("%v not saved: %v", name, err)
+				}
+			}
+		})
+	}
+}
+
+func Test_typeExt(t *testing.T) {
+	tests := []struct {
+		name   string
+		t      string
+		urlExt string
+		want   string
+	}{
+		{
+			name:   "Test html",
+			t:      "text/html",
+			urlExt: ".php",
+			want:   ".html",
+		},
+		{
+			name:   "Test url extension of the type is kept",
+			t:      "text/html",
+			urlExt: ".htm",
+			want:   ".htm",
+		},
+		{
+			name: "Test other type",
+			t:    "image/png",
+			want: ".png",
+		},
+		{
+			name: "Test unknown type",
+			t:    "",
+			want: ".html",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := typeExt(tt.t, tt.urlExt); got != tt.want {
+				t.Errorf("typeExt() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
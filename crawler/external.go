@@ -0,0 +1,47 @@
+package crawler
+
+import (
+	"context"
+	"net/http"
+)
+
+// checkLink checks that the off-site link target, found on the page from,
+// still works. A broken link is returned as a *PageError.
+func (c *Crawler) checkLink(ctx context.Context, target, from string) error {
+	if ctx.Err() != nil {
+		return nil
+	}
+
+	if _, seen := c.visited.LoadOrStore(target, struct{}{}); seen {
+		c.pending.Delete(target)
+		return nil
+	}
+
+	rec := Record{URL: target, External: true, LinkedFrom: from}
+	defer func() {
+		c.addRecord(rec)
+		if rec.Error == "" {
+			c.markDone(target, rec, 0, nil, nil)
+		}
+	}()
+
+	c.Logger.Debug("checking", "url", target)
+	resp, err := c.send(ctx, http.MethodHead, target, nil, "")
+
+	// servers refusing HEAD get a GET
+	if err != nil && resp != nil && (resp.status == http.StatusMethodNotAllowed || resp.status == http.StatusNotImplemented) {
+		resp, err = c.download(ctx, target)
+	}
+	if resp != nil {
+		rec.StatusCode = resp.status
+		rec.ContentType = resp.contentType
+	}
+	if err != nil {
+		c.Logger.Warn("broken external link", "url", target, "linked_from", from, "err", err)
+		rec.Error = err.Error()
+		return &PageError{URL: target, Err: err}
+	}
+	c.Logger.Info("external link ok", "url", target, "status", resp.status)
+
+	return nil
+}
@@ -0,0 +1,103 @@
+package crawler
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+)
+
+func TestCrawler_RunCheckExternal(t *testing.T) {
+	var mutex sync.Mutex
+	crawled := map[string]bool{}
+	external := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		mutex.Lock()
+		crawled[r.Method+" "+r.URL.Path] = true
+		mutex.Unlock()
+		switch r.URL.Path {
+		case "/ok":
+			fmt.Fprint(w, `<a href="/next">next</a>`)
+		case "/no-head":
+			if r.Method == http.MethodHead {
+				w.WriteHeader(http.StatusMethodNotAllowed)
+				return
+			}
+			fmt.Fprint(w, `<p>no head</p>`)
+		default:
+			http.NotFound(w, r)
+		}
+	}))
+	defer external.Close()
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/docs":
+			fmt.Fprintf(w, `<a href="%[1]v/ok">ok</a><a href="%[1]v/ok#top">ok</a><a href="%[1]v/missing">missing</a><a href="%[1]v/no-head">no head</a>`, external.URL)
+		default:
+			http.NotFound(w, r)
+		}
+	}))
+	defer server.Close()
+
+	c := New(server.URL+"/docs", t.TempDir())
+	c.IgnoreRobots = true
+	c.Retries = 0
+	c.CheckExternal = true
+	// the broken link fails the crawl like a broken page
+	c.Run(context.Background())
+
+	records := map[string]Record{}
+	for _, rec := range c.Records() {
+		records[rec.URL] = rec
+	}
+
+	tests := []struct {
+		name       string
+		url        string
+		wantStatus int
+		wantBroken bool
+	}{
+		{
+			name:       "Test working link",
+			url:        external.URL + "/ok",
+			wantStatus: http.StatusOK,
+		},
+		{
+			name:       "Test broken link",
+			url:        external.URL + "/missing",
+			wantStatus: http.StatusNotFound,
+			wantBroken: true,
+		},
+		{
+			name:       "Test server refusing HEAD gets a GET",
+			url:        external.URL + "/no-head",
+			wantStatus: http.StatusOK,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			rec, ok := records[tt.url]
+			if !ok {
+				t.Fatalf("no record of %v", tt.url)
+			}
+			if !rec.External || rec.LinkedFrom != server.URL+"/docs" {
+				t.Errorf("record = %+v, want an external link from %v", rec, server.URL+"/docs")
+			}
+			if rec.StatusCode != tt.wantStatus {
+				t.Errorf("status = %v, want %v", rec.StatusCode, tt.wantStatus)
+			}
+			if (rec.Error != "") != tt.wantBroken {
+				t.Errorf("error = %q, wantBroken %v", rec.Error, tt.wantBroken)
+			}
+		})
+	}
+
+	if crawled["GET /ok"] || crawled["HEAD /next"] || crawled["GET /next"] {
+		t.Errorf("external site was crawled: %v", crawled)
+	}
+	if st := c.Stats(); st.External != 3 || st.BrokenExternal != 1 {
+		t.Errorf("Stats() external = %v, broken = %v, want 3 and 1", st.External, st.BrokenExternal)
+	}
+}
@@ -0,0 +1,38 @@
+package crawler
+
+// fdReserve is the file descriptors set aside from MaxFileDescriptors for
+// what isn't a worker's: stdio, the logs, the state and WARC files, DNS
+// lookups and robots.txt fetches.
+const fdReserve = 16
+
+// fitFileDescriptors returns how many workers fit in MaxFileDescriptors,
+// n at most, and how many output files they may then hold open at once.
+// Each worker needs a connection and a file, the idle keep-alive
+// connections come on top.
+func (c *Crawler) fitFileDescriptors(n int) (workers, files int) {
+	avail := c.MaxFileDescriptors - fdReserve - c.MaxIdleConnsPerHost
+	if avail < 2 {
+		avail = 2
+	}
+
+	workers = n
+	if 2*workers > avail {
+		workers = avail / 2
+		c.Logger.Warn("lowering concurrency to fit the file descriptor limit",
+			"max_file_descriptors", c.MaxFileDescriptors, "concurrency", n, "lowered_to", workers)
+	}
+
+	return workers, avail - workers
+}
+
+// openFile waits for one of the output files allowed open at once and
+// returns the func giving it back.
+func (c *Crawler) openFile() func() {
+	if c.openFiles == nil {
+		return func() {}
+	}
+
+	c.openFiles <- struct{}{}
+
+	return func() { <-c.openFiles }
+}
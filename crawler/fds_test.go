@@ -0,0 +1,99 @@
+package crawler
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestCrawler_fitFileDescriptors(t *testing.T) {
+	tests := []struct {
+		name        string
+		limit       int
+		idle        int
+		concurrency int
+		wantWorkers int
+		wantFiles   int
+	}{
+		{
+			name:        "Test concurrency within the limit is kept",
+			limit:       256,
+			idle:        10,
+			concurrency: 10,
+			wantWorkers: 10,
+			wantFiles:   220,
+		},
+		{
+			name:        "Test concurrency over the limit is lowered",
+			limit:       64,
+			idle:        10,
+			concurrency: 100,
+			wantWorkers: 19,
+			wantFiles:   19,
+		},
+		{
+			name:        "Test a limit below the reserve leaves one worker",
+			limit:       8,
+			idle:        10,
+			concurrency: 10,
+			wantWorkers: 1,
+			wantFiles:   1,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			c := New("", "")
+			c.Logger = slog.New(slog.NewTextHandler(io.Discard, nil))
+			c.MaxFileDescriptors = tt.limit
+			c.MaxIdleConnsPerHost = tt.idle
+
+			workers, files := c.fitFileDescriptors(tt.concurrency)
+			if workers != tt.wantWorkers || files != tt.wantFiles {
+				t.Errorf("fitFileDescriptors() = %v, %v, want %v, %v", workers, files, tt.wantWorkers, tt.wantFiles)
+			}
+		})
+	}
+}
+
+func TestCrawler_RunMaxFileDescriptors(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/docs":
+			var links strings.Builder
+			for i := 0; i < 20; i++ {
+				fmt.Fprintf(&links, `<a href="/docs/%v">%v</a><img src="/docs/%v.png">`, i, i, i)
+			}
+			fmt.Fprint(w, "<p>"+links.String()+"</p>")
+		default:
+			if strings.HasSuffix(r.URL.Path, ".png") {
+				w.Header().Set("Content-Type", "image/png")
+				fmt.Fprint(w, "png")
+				return
+			}
+			fmt.Fprint(w, "<p>page</p>")
+		}
+	}))
+	defer server.Close()
+
+	var logs strings.Builder
+	c := New(server.URL+"/docs", t.TempDir())
+	c.IgnoreRobots = true
+	c.Assets = true
+	c.Logger = slog.New(slog.NewTextHandler(&logs, nil))
+	c.MaxFileDescriptors = 30
+	if err := c.Run(context.Background()); err != nil {
+		t.Fatalf("Run() error = %v", err)
+	}
+
+	if got := len(c.Records()); got != 41 {
+		t.Errorf("len(Records()) = %v, want 41", got)
+	}
+	if !strings.Contains(logs.String(), "lowering concurrency") {
+		t.Errorf("no warning logged about the lowered concurrency")
+	}
+}
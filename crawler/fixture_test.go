@@ -0,0 +1,175 @@
+package crawler
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"os"
+	"path/filepath"
+	"reflect"
+	"sort"
+	"strings"
+	"testing"
+)
+
+// fixtureSite serves the pages under testdata/site, plus /old, a permanent
+// redirect to one of them.
+func fixtureSite(t *testing.T) *httptest.Server {
+	mux := http.NewServeMux()
+	mux.Handle("/", http.FileServer(http.Dir(filepath.Join("testdata", "site"))))
+	mux.Handle("/old", http.RedirectHandler("/docs/intro.html", http.StatusMovedPermanently))
+
+	server := httptest.NewServer(mux)
+	t.Cleanup(server.Close)
+
+	return server
+}
+
+func TestCrawler_RunFixtureSite(t *testing.T) {
+	server := fixtureSite(t)
+
+	wantFiles := []string{
+		filepath.Join("about.html", "about.html.html"),
+		filepath.Join("docs", "index.html"),
+		filepath.Join("docs", "intro.html", "intro.html.html"),
+		"index.html",
+	}
+	// the url, its status and where it redirected to
+	wantVisited := []string{
+		"URL 200",
+		"URL/about.html 200",
+		"URL/docs 200",
+		"URL/docs/intro.html 200",
+		"URL/docs/missing.html 404",
+		"URL/old 200 URL/docs/intro.html",
+	}
+	wantErr := "URL/docs/missing.html: invalid status code 404"
+
+	// every strategy reaches the same pages
+	tests := []struct {
+		name     string
+		strategy string
+	}{
+		{
+			name:     "Test depth-first crawl of the fixture site",
+			strategy: StrategyDFS,
+		},
+		{
+			name:     "Test breadth-first crawl of the fixture site",
+			strategy: StrategyBFS,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			dir := t.TempDir()
+			c := New(server.URL+"/", dir)
+			c.IgnoreRobots = true
+			c.Strategy = tt.strategy
+			c.Sequential = true
+			err := c.Run(context.Background())
+
+			var pageErrs PageErrors
+			if !errors.As(err, &pageErrs) || err.Error() != strings.ReplaceAll(wantErr, "URL", server.URL) {
+				t.Errorf("Run() error = %v, want %v", err, wantErr)
+			}
+
+			files := []string{}
+			visited := []string{}
+			for _, rec := range c.Records() {
+				if rec.Path != "" {
+					rel, err := filepath.Rel(dir, rec.Path)
+					if err != nil {
+						t.Fatal(err)
+					}
+					if _, err := os.Stat(rec.Path); err != nil {
+						t.Errorf("%v not saved: %v", rec.URL, err)
+					}
+					files = append(files, rel)
+				}
+
+				v := strings.TrimSpace(fmt.Sprintf("%v %v %v", rec.URL, rec.StatusCode, rec.FinalURL))
+				visited = append(visited, strings.ReplaceAll(v, server.URL, "URL"))
+			}
+			sort.Strings(files)
+
+			if !reflect.DeepEqual(files, wantFiles) {
+				t.Errorf("saved files = %v, want %v", files, wantFiles)
+			}
+			if !reflect.DeepEqual(visited, wantVisited) {
+				t.Errorf("visited = %v, want %v", visited, wantVisited)
+			}
+		})
+	}
+}
+
+func TestCrawler_extractUrlsFixtureSite(t *testing.T) {
+	tests := []struct {
+		name         string
+		file         string
+		url          string
+		want         []string
+		wantExternal []string
+	}{
+		{
+			name: "Test relative, fragment and redirecting links of the home page",
+			file: "index.html",
+			url:  "https://example.com/",
+			want: []string{
+				"https://example.com/about.html",
+				"https://example.com/docs/",
+				"https://example.com/docs/intro.html",
+				"https://example.com/old",
+			},
+			wantExternal: []string{"https://other.example/elsewhere"},
+		},
+		{
+			name: "Test parent links are out of the path scope",
+			file: filepath.Join("docs", "index.html"),
+			url:  "https://example.com/docs/",
+			want: []string{
+				"https://example.com/docs/intro.html",
+				"https://example.com/docs/missing.html",
+			},
+			wantExternal: []string{},
+		},
+		{
+			name:         "Test protocol-relative link is external",
+			file:         filepath.Join("docs", "intro.html"),
+			url:          "https://example.com/docs/intro.html",
+			want:         []string{},
+			wantExternal: []string{"https://other.example/other"},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			data, err := os.ReadFile(filepath.Join("testdata", "site", tt.file))
+			if err != nil {
+				t.Fatal(err)
+			}
+			doc, err := parseHTML(data)
+			if err != nil {
+				t.Fatalf("parseHTML() error = %v", err)
+			}
+			parsedURL, err := url.Parse(tt.url)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			c := New(tt.url, t.TempDir())
+			got, external, err := c.extractUrls(doc, parsedURL)
+			if err != nil {
+				t.Fatalf("extractUrls() error = %v", err)
+			}
+
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("extractUrls() = %v, want %v", got, tt.want)
+			}
+			if !reflect.DeepEqual(external, tt.wantExternal) {
+				t.Errorf("extractUrls() external = %v, want %v", external, tt.wantExternal)
+			}
+		})
+	}
+}
@@ -0,0 +1,94 @@
+package crawler
+
+import (
+	"bytes"
+	"fmt"
+	"os"
+	"sort"
+	"strconv"
+	"sync"
+)
+
+// edge is a link from one page to another.
+type edge struct {
+	from, to string
+}
+
+// linkGraph collects the links found on every page.
+type linkGraph struct {
+	mutex sync.Mutex
+	edges map[edge]struct{}
+}
+
+func (g *linkGraph) add(from string, to []string) {
+	g.mutex.Lock()
+	defer g.mutex.Unlock()
+
+	if g.edges == nil {
+		g.edges = map[edge]struct{}{}
+	}
+	for _, u := range to {
+		g.edges[edge{from: from, to: u}] = struct{}{}
+	}
+}
+
+// sorted returns the edges ordered by source, then target.
+func (g *linkGraph) sorted() []edge {
+	g.mutex.Lock()
+	edges := make([]edge, 0, len(g.edges))
+	for e := range g.edges {
+		edges = append(edges, e)
+	}
+	g.mutex.Unlock()
+
+	sort.Slice(edges, func(i, j int) bool {
+		if edges[i].from != edges[j].from {
+			return edges[i].from < edges[j].from
+		}
+		return edges[i].to < edges[j].to
+	})
+
+	return edges
+}
+
+// WriteGraph writes the links between the crawled pages to fileName as a
+// GraphViz DOT digraph, the pages labeled with their titles. Links are
+// only recorded with Graph set.
+func (c *Crawler) WriteGraph(fileName string) error {
+	titles := map[string]string{}
+	for _, rec := range c.Records() {
+		if rec.Title == "" {
+			continue
+		}
+		if rec.FinalURL != "" {
+			titles[rec.FinalURL] = rec.Title
+		} else {
+			titles[rec.URL] = rec.Title
+		}
+	}
+
+	edges := c.graph.sorted()
+	nodes := []string{}
+	seen := map[string]struct{}{}
+	for _, e := range edges {
+		for _, u := range []string{e.from, e.to} {
+			if _, ok := seen[u]; !ok && titles[u] != "" {
+				seen[u] = struct{}{}
+				nodes = append(nodes, u)
+			}
+		}
+	}
+	sort.Strings(nodes)
+
+	var buf bytes.Buffer
+	buf.WriteString("digraph crawl {\n")
+	for _, u := range nodes {
+		fmt.Fprintf(&buf, "  %v [label=%v];\n", strconv.Quote(u), strconv.Quote(titles[u]))
+	}
+	for _, e := range edges {
+		fmt.Fprintf(&buf, "  %v -> %v;\n", strconv.Quote(e.from), strconv.Quote(e.to))
+	}
+	buf.WriteString("}\n")
+
+	return os.WriteFile(fileName, buf.Bytes(), 0644)
+}
@@ -0,0 +1,77 @@
+package crawler
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestCrawler_WriteGraph(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/docs":
+			fmt.Fprint(w, `<title>Docs</title><a href="/docs/a">a</a><a href="/docs/b">b</a>`)
+		case "/docs/a":
+			fmt.Fprint(w, `<title>Page "A"</title><a href="/docs/a/c">c</a><a href="/docs/a">self</a>`)
+		default:
+			fmt.Fprint(w, `<p>page</p>`)
+		}
+	}))
+	defer server.Close()
+
+	tests := []struct {
+		name  string
+		graph bool
+		want  []string
+	}{
+		{
+			name:  "Test links between pages are written",
+			graph: true,
+			want: []string{
+				`digraph crawl {`,
+				`  "URL/docs" [label="Docs"];`,
+				`  "URL/docs/a" [label="Page \"A\""];`,
+				`  "URL/docs" -> "URL/docs/a";`,
+				`  "URL/docs" -> "URL/docs/b";`,
+				`  "URL/docs/a" -> "URL/docs/a/c";`,
+				`}`,
+			},
+		},
+		{
+			name: "Test links aren't recorded by default",
+			want: []string{
+				`digraph crawl {`,
+				`}`,
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			c := New(server.URL+"/docs", t.TempDir())
+			c.IgnoreRobots = true
+			c.Graph = tt.graph
+			if err := c.Run(context.Background()); err != nil {
+				t.Fatalf("Run() error = %v", err)
+			}
+
+			fileName := filepath.Join(t.TempDir(), "crawl.dot")
+			if err := c.WriteGraph(fileName); err != nil {
+				t.Fatalf("WriteGraph() error = %v", err)
+			}
+
+			data, err := os.ReadFile(fileName)
+			if err != nil {
+				t.Fatal(err)
+			}
+			want := strings.ReplaceAll(strings.Join(tt.want, "\n")+"\n", "URL", server.URL)
+			if string(data) != want {
+				t.Errorf("WriteGraph() wrote\n%v\nwant\n%v", string(data), want)
+			}
+		})
+	}
+}
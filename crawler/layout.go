@@ -0,0 +1,73 @@
+package crawler
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"net/url"
+	"path"
+	"path/filepath"
+	"strings"
+)
+
+// The output layouts a Crawler supports.
+const (
+	// LayoutMirror saves pages under their url path, the target's host at
+	// the top of the output dir and other hosts in directories of their own.
+	LayoutMirror = "mirror"
+
+	// LayoutFlat saves every page and asset in the output dir itself, named
+	// after the hash of its url, and lists them in layoutManifest.
+	LayoutFlat = "flat"
+
+	// LayoutHostname is LayoutMirror with the target's host in a directory
+	// of its own too.
+	LayoutHostname = "hostname"
+)
+
+// layoutManifest maps every url to its file with LayoutFlat.
+const layoutManifest = "manifest.json"
+
+// customPath returns the directory and file name PathFor gives u, kept
+// inside the output dir. Pages drop their .html extension, which is added
+// back when saving.
+func (c *Crawler) customPath(u *url.URL, page bool) (string, string) {
+	p := filepath.Join(c.dir, filepath.FromSlash(path.Clean("/"+c.PathFor(u.String()))))
+	if page {
+		p = strings.TrimSuffix(p, ".html")
+	}
+
+	return filepath.Dir(p), filepath.Base(p)
+}
+
+// flatName returns the name u is saved under with LayoutFlat.
+func (c *Crawler) flatName(u *url.URL) string {
+	sum := sha256.Sum256([]byte(c.pageKey(u)))
+	return hex.EncodeToString(sum[:16])
+}
+
+// writeLayoutManifest lists the file of every url saved in this crawl,
+// relative to the output dir.
+func (c *Crawler) writeLayoutManifest() error {
+	manifest := map[string]string{}
+	for _, rec := range c.Records() {
+		if rec.Path == "" {
+			continue
+		}
+		rel, err := filepath.Rel(c.dir, rec.Path)
+		if err != nil {
+			return err
+		}
+		manifest[rec.URL] = filepath.ToSlash(rel)
+		if rec.FinalURL != "" {
+			manifest[rec.FinalURL] = filepath.ToSlash(rel)
+		}
+	}
+
+	data, err := json.MarshalIndent(manifest, "", "  ")
+	if err != nil {
+		return err
+	}
+
+	return c.store().Put(filepath.Join(c.dir, layoutManifest), data, ObjectMeta{ContentType: "application/json"})
+}
@@ -0,0 +1,84 @@
+package crawler
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestCrawler_RunFlatLayout(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/docs":
+			fmt.Fprint(w, `<a href="/docs/a/b/c">deep</a><img src="/img/logo.png">`)
+		case "/docs/a/b/c":
+			fmt.Fprint(w, `<p>deep</p>`)
+		case "/img/logo.png":
+			w.Header().Set("Content-Type", "image/png")
+			fmt.Fprint(w, "\x89PNG")
+		default:
+			http.NotFound(w, r)
+		}
+	}))
+	defer server.Close()
+
+	dir := t.TempDir()
+	c := New(server.URL+"/docs", dir)
+	c.IgnoreRobots = true
+	c.Assets = true
+	c.Layout = LayoutFlat
+	if err := c.Run(context.Background()); err != nil {
+		t.Fatalf("Run() error = %v", err)
+	}
+
+	data, err := os.ReadFile(filepath.Join(dir, layoutManifest))
+	if err != nil {
+		t.Fatal(err)
+	}
+	manifest := map[string]string{}
+	if err := json.Unmarshal(data, &manifest); err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name    string
+		url     string
+		wantExt string
+	}{
+		{
+			name:    "Test target is in the dir itself",
+			url:     server.URL + "/docs",
+			wantExt: ".html",
+		},
+		{
+			name:    "Test deep page is in the dir itself",
+			url:     server.URL + "/docs/a/b/c",
+			wantExt: ".html",
+		},
+		{
+			name:    "Test asset keeps its extension",
+			url:     server.URL + "/img/logo.png",
+			wantExt: ".png",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			file, ok := manifest[tt.url]
+			if !ok {
+				t.Fatalf("manifest %v has no %v", manifest, tt.url)
+			}
+			if strings.Contains(file, "/") || filepath.Ext(file) != tt.wantExt {
+				t.Errorf("manifest maps %v to %v, want a %v file in the dir itself", tt.url, file, tt.wantExt)
+			}
+			if _, err := os.Stat(filepath.Join(dir, file)); err != nil {
+				t.Errorf("file of %v: %v", tt.url, err)
+			}
+		})
+	}
+}
@@ -0,0 +1,162 @@
+package crawler
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// metaSuffix is appended to the name of a saved page, without its .html
+// extension, to name the file holding its metadata.
+const metaSuffix = ".meta.json"
+
+// headersSuffix is appended to the name of a saved page, like metaSuffix,
+// to name the file holding its raw headers with SaveRawHeaders.
+const headersSuffix = ".headers"
+
+// pageMeta is the metadata saved next to each page, so the mirror on disk
+// describes itself and later crawls can revalidate it.
+type pageMeta struct {
+	URL         string      `json:"url"`
+	FinalURL    string      `json:"final_url,omitempty"`
+	StatusCode  int         `json:"status_code"`
+	ContentType string      `json:"content_type,omitempty"`
+	Header      http.Header `json:"header"`
+	FetchedAt   time.Time   `json:"fetched_at"`
+}
+
+// saveMeta writes the metadata of a page downloaded as resp next to it,
+// and its raw headers with SaveRawHeaders.
+func (c *Crawler) saveMeta(filePath, fileName string, rec Record, resp *response) error {
+	// session cookies don't belong in a mirror that may be shared
+	header := resp.header.Clone()
+	header.Del("Set-Cookie")
+
+	err := c.writeMeta(filePath, fileName, &pageMeta{
+		URL:         rec.URL,
+		FinalURL:    rec.FinalURL,
+		StatusCode:  resp.status,
+		ContentType: resp.contentType,
+		Header:      header,
+		FetchedAt:   resp.fetchedAt.UTC(),
+	})
+	if err != nil || !c.SaveRawHeaders {
+		return err
+	}
+
+	return c.save(filePath, fileName+headersSuffix, rawHeaders(resp.proto, resp.status, header), ObjectMeta{
+		URL:         rec.URL,
+		ContentType: "text/plain; charset=utf-8",
+	})
+}
+
+// rawHeaders returns a status line and header in HTTP wire format, ending
+// with the blank line that separates them from the body.
+func rawHeaders(proto string, status int, header http.Header) []byte {
+	if proto == "" {
+		proto = "HTTP/1.1"
+	}
+
+	var buf bytes.Buffer
+	fmt.Fprintf(&buf, "%v %v %v\r\n", proto, status, http.StatusText(status))
+	header.Write(&buf)
+	buf.WriteString("\r\n")
+
+	return buf.Bytes()
+}
+
+// refreshMeta updates the metadata of a page the server answered 304 for:
+// the headers it sent replace the saved ones and the page counts as
+// fetched now.
+func (c *Crawler) refreshMeta(filePath, fileName string, resp *response) error {
+	meta := c.readMeta(filePath, fileName)
+	if meta == nil {
+		return nil
+	}
+
+	for k, values := range resp.header {
+		if k != "Set-Cookie" {
+			meta.Header[k] = values
+		}
+	}
+	meta.FetchedAt = resp.fetchedAt.UTC()
+
+	return c.writeMeta(filePath, fileName, meta)
+}
+
+func (c *Crawler) writeMeta(filePath, fileName string, meta *pageMeta) error {
+	data, err := json.MarshalIndent(meta, "", "  ")
+	if err != nil {
+		return err
+	}
+
+	return c.save(filePath, fileName+metaSuffix, data, ObjectMeta{URL: meta.URL, ContentType: "application/json"})
+}
+
+// readMeta reads the metadata saved next to a page. It returns nil when
+// there is none.
+func (c *Crawler) readMeta(filePath, fileName string) *pageMeta {
+	data, ok := c.store().Get(filepath.Join(filePath, fileName+metaSuffix))
+	if !ok {
+		return nil
+	}
+
+	var meta pageMeta
+	if err := json.Unmarshal(data, &meta); err != nil {
+		return nil
+	}
+
+	return &meta
+}
+
+// fresh reports whether the saved copy can still be used without asking
+// the server, going by its Cache-Control max-age or Expires header. Pages
+// without either are always stale.
+func (m *pageMeta) fresh(now time.Time) bool {
+	for _, directive := range strings.Split(m.Header.Get("Cache-Control"), ",") {
+		directive = strings.ToLower(strings.TrimSpace(directive))
+		if directive == "no-cache" || directive == "no-store" {
+			return false
+		}
+		if v, ok := strings.CutPrefix(directive, "max-age="); ok {
+			if seconds, err := strconv.Atoi(v); err == nil {
+				return now.Before(m.FetchedAt.Add(time.Duration(seconds) * time.Second))
+			}
+		}
+	}
+
+	if expires, err := http.ParseTime(m.Header.Get("Expires")); err == nil {
+		return now.Before(expires)
+	}
+
+	return false
+}
+
+// validators returns the conditional headers to revalidate a saved page
+// with, and whether it needs revalidating at all. Pages saved without
+// metadata are used as they are.
+func (c *Crawler) validators(savedContent []byte, filePath, fileName string) (http.Header, bool) {
+	if savedContent == nil {
+		return nil, false
+	}
+
+	meta := c.readMeta(filePath, fileName)
+	if meta == nil || (!c.Refresh && meta.fresh(time.Now())) {
+		return nil, false
+	}
+
+	header := http.Header{}
+	if etag := meta.Header.Get("ETag"); etag != "" {
+		header.Set("If-None-Match", etag)
+	}
+	if lastModified := meta.Header.Get("Last-Modified"); lastModified != "" {
+		header.Set("If-Modified-Since", lastModified)
+	}
+
+	return header, true
+}
@@ -0,0 +1,233 @@
+package crawler
+
+import (
+	"bufio"
+	"context"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestCrawler_RunMeta(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/old":
+			http.Redirect(w, r, "/new", http.StatusMovedPermanently)
+		default:
+			w.Header().Set("Content-Type", "text/html; charset=utf-8")
+			w.Header().Set("ETag", `"v1"`)
+			http.SetCookie(w, &http.Cookie{Name: "session", Value: "secret"})
+			fmt.Fprint(w, `<p>page</p>`)
+		}
+	}))
+	defer server.Close()
+
+	tests := []struct {
+		name         string
+		path         string
+		wantDir      string
+		wantName     string
+		wantFinalURL string
+	}{
+		{
+			name:     "Test metadata is saved next to the page",
+			path:     "/page",
+			wantDir:  "page",
+			wantName: "page",
+		},
+		{
+			name:         "Test metadata of a redirected page",
+			path:         "/old",
+			wantDir:      "new",
+			wantName:     "new",
+			wantFinalURL: server.URL + "/new",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			dir := t.TempDir()
+			c := New(server.URL+tt.path, dir)
+			c.IgnoreRobots = true
+			if err := c.Run(context.Background()); err != nil {
+				t.Fatalf("Run() error = %v", err)
+			}
+
+			meta := c.readMeta(filepath.Join(dir, tt.wantDir), tt.wantName)
+			if meta == nil {
+				t.Fatalf("readMeta() = nil, want the metadata of %v", tt.path)
+			}
+
+			if meta.URL != server.URL+tt.path || meta.FinalURL != tt.wantFinalURL {
+				t.Errorf("readMeta() urls = %v, %v, want %v, %v", meta.URL, meta.FinalURL, server.URL+tt.path, tt.wantFinalURL)
+			}
+			if meta.StatusCode != http.StatusOK || meta.ContentType != "text/html; charset=utf-8" || meta.FetchedAt.IsZero() {
+				t.Errorf("readMeta() = %+v", meta)
+			}
+			if meta.Header.Get("ETag") != `"v1"` || meta.Header.Get("Set-Cookie") != "" {
+				t.Errorf("readMeta() header = %v, want the ETag without cookies", meta.Header)
+			}
+		})
+	}
+}
+
+func TestCrawler_RunRawHeaders(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "text/html; charset=utf-8")
+		w.Header().Set("ETag", `"v1"`)
+		http.SetCookie(w, &http.Cookie{Name: "session", Value: "secret"})
+		fmt.Fprint(w, `<p>page</p>`)
+	}))
+	defer server.Close()
+
+	tests := []struct {
+		name       string
+		rawHeaders bool
+		want       []string
+	}{
+		{
+			name:       "Test headers are saved in wire format",
+			rawHeaders: true,
+			want: []string{
+				"HTTP/1.1 200 OK\r\n",
+				"Content-Type: text/html; charset=utf-8\r\n",
+				"Etag: \"v1\"\r\n",
+			},
+		},
+		{
+			name: "Test headers aren't saved by default",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			dir := t.TempDir()
+			c := New(server.URL+"/page", dir)
+			c.IgnoreRobots = true
+			c.SaveRawHeaders = tt.rawHeaders
+			if err := c.Run(context.Background()); err != nil {
+				t.Fatalf("Run() error = %v", err)
+			}
+
+			body, err := os.ReadFile(filepath.Join(dir, "page", "page.html"))
+			if err != nil || string(body) != `<p>page</p>` {
+				t.Errorf("page = %q, %v, want it unchanged", body, err)
+			}
+
+			fileName := filepath.Join(dir, "page", "page"+headersSuffix)
+			data, err := os.ReadFile(fileName)
+			if !tt.rawHeaders {
+				if err == nil {
+					t.Errorf("%v saved, want no headers file", fileName)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			got := string(data)
+			if !strings.HasPrefix(got, tt.want[0]) || !strings.HasSuffix(got, "\r\n\r\n") || strings.Contains(got, "Set-Cookie") {
+				t.Errorf("%v = %q, want a status line, no cookies and a blank line", fileName, got)
+			}
+			for _, want := range tt.want {
+				if !strings.Contains(got, want) {
+					t.Errorf("%v = %q, want it to contain %q", fileName, got, want)
+				}
+			}
+
+			resp, err := http.ReadResponse(bufio.NewReader(strings.NewReader(got)), nil)
+			if err != nil {
+				t.Fatalf("http.ReadResponse() error = %v", err)
+			}
+			if resp.StatusCode != http.StatusOK || resp.Header.Get("ETag") != `"v1"` {
+				t.Errorf("http.ReadResponse() = %v %v", resp.StatusCode, resp.Header)
+			}
+		})
+	}
+}
+
+func TestCrawler_RunRevalidate(t *testing.T) {
+	type args struct {
+		cacheControl string
+		etag         string
+		refresh      bool
+	}
+	tests := []struct {
+		name       string
+		args       args
+		wantStatus int
+		wantBody   string
+	}{
+		{
+			name:       "Test fresh pages are not requested again",
+			args:       args{cacheControl: "max-age=3600", etag: `"v1"`},
+			wantStatus: 0,
+			wantBody:   "<p>v1</p>",
+		},
+		{
+			name:       "Test stale unchanged pages are kept",
+			args:       args{etag: `"v1"`},
+			wantStatus: http.StatusNotModified,
+			wantBody:   "<p>v1</p>",
+		},
+		{
+			name:       "Test stale changed pages are downloaded again",
+			args:       args{etag: `"v2"`},
+			wantStatus: http.StatusOK,
+			wantBody:   "<p>v2</p>",
+		},
+		{
+			name:       "Test refresh revalidates fresh pages",
+			args:       args{cacheControl: "max-age=3600", etag: `"v2"`, refresh: true},
+			wantStatus: http.StatusOK,
+			wantBody:   "<p>v2</p>",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			etag := `"v1"`
+			server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				if tt.args.cacheControl != "" {
+					w.Header().Set("Cache-Control", tt.args.cacheControl)
+				}
+				w.Header().Set("ETag", etag)
+				if r.Header.Get("If-None-Match") == etag {
+					w.WriteHeader(http.StatusNotModified)
+					return
+				}
+				fmt.Fprintf(w, "<p>%v</p>", strings.Trim(etag, `"`))
+			}))
+			defer server.Close()
+
+			dir := t.TempDir()
+			first := New(server.URL+"/page", dir)
+			first.IgnoreRobots = true
+			if err := first.Run(context.Background()); err != nil {
+				t.Fatalf("Run() error = %v", err)
+			}
+
+			etag = tt.args.etag
+			c := New(server.URL+"/page", dir)
+			c.IgnoreRobots = true
+			c.Refresh = tt.args.refresh
+			if err := c.Run(context.Background()); err != nil {
+				t.Fatalf("Run() error = %v", err)
+			}
+
+			if rec := c.Records()[0]; rec.StatusCode != tt.wantStatus {
+				t.Errorf("Run() status = %v, want %v", rec.StatusCode, tt.wantStatus)
+			}
+
+			body, err := os.ReadFile(filepath.Join(dir, "page", "page.html"))
+			if err != nil {
+				t.Fatal(err)
+			}
+			if string(body) != tt.wantBody {
+				t.Errorf("saved page = %q, want %q", body, tt.wantBody)
+			}
+		})
+	}
+}
@@ -0,0 +1,102 @@
+package crawler
+
+import (
+	"net/http"
+	"strings"
+
+	"golang.org/x/net/html"
+)
+
+// metaRobots returns the noindex and nofollow directives of a page's
+// <meta name="robots"> tags. "none" stands for both.
+func metaRobots(htmlDoc *html.Node) (noindex, nofollow bool) {
+	var f func(*html.Node)
+	f = func(n *html.Node) {
+		if n.Type == html.ElementNode && n.Data == "meta" {
+			name, content := "", ""
+			for _, a := range n.Attr {
+				switch a.Key {
+				case "name":
+					name = strings.ToLower(strings.TrimSpace(a.Val))
+				case "content":
+					content = strings.ToLower(a.Val)
+				}
+			}
+
+			if name == "robots" {
+				i, f := robotsDirectives(content)
+				noindex, nofollow = noindex || i, nofollow || f
+			}
+		}
+		for child := n.FirstChild; child != nil; child = child.NextSibling {
+			f(child)
+		}
+	}
+	f(htmlDoc)
+
+	return noindex, nofollow
+}
+
+// robotsTag returns the noindex and nofollow directives of the
+// X-Robots-Tag headers a page was served with. A header naming a user agent,
+// as in "otherbot: noindex", only counts when agent contains it.
+func robotsTag(header http.Header, agent string) (noindex, nofollow bool) {
+	agent = strings.ToLower(agent)
+	for _, v := range header.Values("X-Robots-Tag") {
+		v = strings.ToLower(v)
+
+		// directives taking a value have a colon too, but no user agent
+		// comes after a comma
+		if name, rest, ok := strings.Cut(v, ":"); ok && !strings.Contains(name, ",") && !robotsValueDirectives[strings.TrimSpace(name)] {
+			if !strings.Contains(agent, strings.TrimSpace(name)) {
+				continue
+			}
+			v = rest
+		}
+
+		i, f := robotsDirectives(v)
+		noindex, nofollow = noindex || i, nofollow || f
+	}
+
+	return noindex, nofollow
+}
+
+// robotsValueDirectives are the robots directives written as name: value.
+var robotsValueDirectives = map[string]bool{
+	"unavailable_after": true,
+	"max-snippet":       true,
+	"max-image-preview": true,
+	"max-video-preview": true,
+}
+
+// robotsDirectives returns the noindex and nofollow directives of a
+// lowercase, comma separated list. "none" stands for both.
+func robotsDirectives(list string) (noindex, nofollow bool) {
+	for _, d := range strings.Split(list, ",") {
+		switch strings.TrimSpace(d) {
+		case "noindex":
+			noindex = true
+		case "nofollow":
+			nofollow = true
+		case "none":
+			noindex, nofollow = true, true
+		}
+	}
+
+	return noindex, nofollow
+}
+
+// isNofollow reports whether a link carries rel="nofollow".
+func isNofollow(n *html.Node) bool {
+	for _, a := range n.Attr {
+		if a.Key == "rel" {
+			for _, rel := range strings.Fields(strings.ToLower(a.Val)) {
+				if rel == "nofollow" {
+					return true
+				}
+			}
+		}
+	}
+
+	return false
+}
@@ -0,0 +1,154 @@
+package crawler
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"testing"
+)
+
+func Test_metaRobots(t *testing.T) {
+	tests := []struct {
+		name         string
+		page         string
+		wantNoindex  bool
+		wantNofollow bool
+	}{
+		{
+			name: "Test no meta tag",
+			page: `<head><meta name="description" content="nofollow"></head>`,
+		},
+		{
+			name:         "Test nofollow",
+			page:         `<head><meta name="robots" content="nofollow"></head>`,
+			wantNofollow: true,
+		},
+		{
+			name:        "Test noindex with other directives",
+			page:        `<head><meta name="ROBOTS" content="noarchive, NOINDEX"></head>`,
+			wantNoindex: true,
+		},
+		{
+			name:         "Test none",
+			page:         `<head><meta name="robots" content="none"></head>`,
+			wantNoindex:  true,
+			wantNofollow: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			doc, err := parseHTML([]byte(tt.page))
+			if err != nil {
+				t.Fatalf("parseHTML() error = %v", err)
+			}
+
+			noindex, nofollow := metaRobots(doc)
+			if noindex != tt.wantNoindex || nofollow != tt.wantNofollow {
+				t.Errorf("metaRobots() = %v, %v, want %v, %v", noindex, nofollow, tt.wantNoindex, tt.wantNofollow)
+			}
+		})
+	}
+}
+
+func Test_robotsTag(t *testing.T) {
+	tests := []struct {
+		name         string
+		values       []string
+		wantNoindex  bool
+		wantNofollow bool
+	}{
+		{
+			name: "Test no header",
+		},
+		{
+			name:         "Test directives",
+			values:       []string{"NoIndex, nofollow"},
+			wantNoindex:  true,
+			wantNofollow: true,
+		},
+		{
+			name:         "Test several headers",
+			values:       []string{"noarchive", "none"},
+			wantNoindex:  true,
+			wantNofollow: true,
+		},
+		{
+			name:   "Test other agents are ignored",
+			values: []string{"otherbot: nofollow"},
+		},
+		{
+			name:         "Test our agent",
+			values:       []string{"Web-Crawler: nofollow"},
+			wantNofollow: true,
+		},
+		{
+			name:        "Test directives with a value",
+			values:      []string{"unavailable_after: 25 Jun 2010 15:00:00 PST", "max-snippet: 20, noindex"},
+			wantNoindex: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			header := http.Header{}
+			for _, v := range tt.values {
+				header.Add("X-Robots-Tag", v)
+			}
+
+			noindex, nofollow := robotsTag(header, DefaultUserAgent)
+			if noindex != tt.wantNoindex || nofollow != tt.wantNofollow {
+				t.Errorf("robotsTag() = %v, %v, want %v, %v", noindex, nofollow, tt.wantNoindex, tt.wantNofollow)
+			}
+		})
+	}
+}
+
+func TestCrawler_RunRobotsTag(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/docs":
+			w.Header().Set("X-Robots-Tag", "nofollow")
+			fmt.Fprint(w, `<a href="/docs/a">a</a>`)
+		case "/docs/a":
+			fmt.Fprint(w, `<p>a</p>`)
+		default:
+			http.NotFound(w, r)
+		}
+	}))
+	defer server.Close()
+
+	tests := []struct {
+		name        string
+		ignoreMeta  bool
+		wantVisited []string
+	}{
+		{
+			name:        "Test links of a nofollow page aren't followed",
+			wantVisited: []string{server.URL + "/docs"},
+		},
+		{
+			name:        "Test header is ignored with the meta tags",
+			ignoreMeta:  true,
+			wantVisited: []string{server.URL + "/docs", server.URL + "/docs/a"},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			c := New(server.URL+"/docs", t.TempDir())
+			c.IgnoreRobots = true
+			c.IgnoreMetaRobots = tt.ignoreMeta
+			if err := c.Run(context.Background()); err != nil {
+				t.Fatalf("Run() error = %v", err)
+			}
+
+			got := []string{}
+			for _, rec := range c.Records() {
+				got = append(got, rec.URL)
+			}
+			if !reflect.DeepEqual(got, tt.wantVisited) {
+				t.Errorf("Run() visited %v, want %v", got, tt.wantVisited)
+			}
+		})
+	}
+}
@@ -0,0 +1,46 @@
+package crawler
+
+import (
+	"fmt"
+	"io"
+	"net/http"
+	"sort"
+)
+
+// MetricsHandler serves the counters of the crawl in the Prometheus text
+// format, for a long-running crawl to be scraped. They are the ones Stats
+// and the progress line read.
+func (c *Crawler) MetricsHandler() http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "text/plain; version=0.0.4; charset=utf-8")
+		c.writeMetrics(w)
+	})
+}
+
+func (c *Crawler) writeMetrics(w io.Writer) {
+	metric := func(name, kind, help string, value int64) {
+		fmt.Fprintf(w, "# HELP %v %v\n# TYPE %v %v\n%v %v\n", name, help, name, kind, name, value)
+	}
+
+	metric("crawler_pages_total", "counter", "Pages downloaded.", c.stats.pages.Load())
+	metric("crawler_assets_total", "counter", "Assets downloaded.", c.stats.assets.Load())
+	metric("crawler_cached_total", "counter", "Pages and assets kept from a previous crawl.", c.stats.cached.Load())
+	metric("crawler_bytes_total", "counter", "Bytes downloaded.", c.stats.bytes.Load())
+	metric("crawler_errors_total", "counter", "Pages and assets that failed.", c.stats.errors.Load())
+	metric("crawler_soft_404_total", "counter", "Pages skipped as soft 404s.", c.stats.soft404.Load())
+
+	c.stats.mutex.Lock()
+	codes := make([]int, 0, len(c.stats.byStatus))
+	for code := range c.stats.byStatus {
+		codes = append(codes, code)
+	}
+	sort.Ints(codes)
+	fmt.Fprintf(w, "# HELP crawler_status_errors_total Pages and assets that failed, by HTTP status.\n# TYPE crawler_status_errors_total counter\n")
+	for _, code := range codes {
+		fmt.Fprintf(w, "crawler_status_errors_total{status=\"%v\"} %v\n", code, c.stats.byStatus[code])
+	}
+	c.stats.mutex.Unlock()
+
+	metric("crawler_active_workers", "gauge", "Workers busy with a page or asset.", c.stats.active.Load())
+	metric("crawler_queue_depth", "gauge", "Urls waiting to be crawled.", int64(c.queue.len()))
+}
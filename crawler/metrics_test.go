@@ -0,0 +1,82 @@
+package crawler
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"golang.org/x/net/html"
+)
+
+func TestCrawler_MetricsHandler(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/docs":
+			fmt.Fprint(w, `<a href="/docs/a">a</a><a href="/docs/missing">missing</a><a href="/docs/gone">gone</a>`)
+		case "/docs/a":
+			fmt.Fprint(w, `<p>a</p>`)
+		case "/docs/gone":
+			http.Error(w, "gone", http.StatusGone)
+		default:
+			http.NotFound(w, r)
+		}
+	}))
+	defer server.Close()
+
+	scrape := func(c *Crawler) string {
+		rec := httptest.NewRecorder()
+		c.MetricsHandler().ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/metrics", nil))
+		if got := rec.Header().Get("Content-Type"); !strings.HasPrefix(got, "text/plain; version=0.0.4") {
+			t.Errorf("Content-Type = %v, want the Prometheus text format", got)
+		}
+		return rec.Body.String()
+	}
+
+	c := New(server.URL+"/docs", t.TempDir())
+	c.IgnoreRobots = true
+	during := ""
+	c.OnPage = func(url string, _ int, _ string, _ []byte, _ *html.Node) error {
+		if strings.HasSuffix(url, "/docs") {
+			during = scrape(c)
+		}
+		return nil
+	}
+	c.Run(context.Background())
+
+	tests := []struct {
+		name      string
+		metrics   string
+		wantLines []string
+	}{
+		{
+			name:      "Test gauges while crawling",
+			metrics:   during,
+			wantLines: []string{"crawler_active_workers 1", "# TYPE crawler_queue_depth gauge"},
+		},
+		{
+			name:    "Test counters once done",
+			metrics: scrape(c),
+			wantLines: []string{
+				"# TYPE crawler_pages_total counter",
+				"crawler_pages_total 2",
+				"crawler_errors_total 2",
+				`crawler_status_errors_total{status="404"} 1`,
+				`crawler_status_errors_total{status="410"} 1`,
+				"crawler_active_workers 0",
+				"crawler_queue_depth 0",
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			for _, line := range tt.wantLines {
+				if !strings.Contains(tt.metrics, line+"\n") {
+					t.Errorf("metrics = %q, want a line %q", tt.metrics, line)
+				}
+			}
+		})
+	}
+}
@@ -0,0 +1,181 @@
+package crawler
+
+import (
+	"bytes"
+	"fmt"
+	"io/fs"
+	"net/url"
+	"path"
+	"path/filepath"
+	"sync"
+
+	"golang.org/x/net/html"
+)
+
+// linkAttrs maps the elements rewritten in mirror mode to their url
+// attribute.
+var linkAttrs = map[string]string{
+	"a":      "href",
+	"area":   "href",
+	"link":   "href",
+	"img":    "src",
+	"script": "src",
+	"iframe": "src",
+}
+
+// mirrorPage is a page saved during this crawl whose links get rewritten.
+type mirrorPage struct {
+	url  *url.URL
+	path string
+}
+
+// mirrorPages collects the pages to rewrite once the crawl is over, when
+// every crawled url has a known location on disk.
+type mirrorPages struct {
+	mutex sync.Mutex
+	pages []mirrorPage
+}
+
+func (m *mirrorPages) add(u *url.URL, path string) {
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+
+	m.pages = append(m.pages, mirrorPage{url: u, path: path})
+}
+
+// rewriteMirror points the links of every page saved in this crawl at the
+// local copies of their targets, so the mirror can be browsed offline.
+// Links to urls that weren't crawled are made absolute. Pages read back from
+// disk were already rewritten by the run that saved them.
+func (c *Crawler) rewriteMirror() {
+	local := map[string]string{}
+	for _, rec := range c.Records() {
+		if rec.Path == "" {
+			continue
+		}
+		for _, u := range []string{rec.URL, rec.FinalURL, rec.Canonical} {
+			if u == "" {
+				continue
+			}
+			local[u] = rec.Path
+			// pages are looked up by their key, which may differ from the
+			// url they were reported as, e.g. in case
+			if !rec.Asset {
+				local[c.visitedKey(u)] = rec.Path
+			}
+		}
+	}
+
+	for _, p := range c.mirror.pages {
+		if err := c.rewritePage(p, local); err != nil {
+			c.Logger.Error("error rewriting", "path", p.path, "err", err)
+			c.addPageError(&PageError{URL: p.url.String(), Err: err})
+		}
+	}
+}
+
+func (c *Crawler) rewritePage(p mirrorPage, local map[string]string) error {
+	data, ok := c.store().Get(p.path)
+	if !ok {
+		return fmt.Errorf("%v: %w", p.path, fs.ErrNotExist)
+	}
+
+	doc, err := parseHTML(data)
+	if err != nil {
+		return err
+	}
+
+	baseURL := findBase(doc, p.url)
+
+	var f func(*html.Node)
+	f = func(n *html.Node) {
+		if n.Type == html.ElementNode {
+			if attr, ok := linkAttrs[n.Data]; ok {
+				for i, a := range n.Attr {
+					if a.Key != attr || a.Val == "" || a.Val[0] == '#' {
+						continue
+					}
+
+					hrefURL, err := url.Parse(a.Val)
+					if err != nil {
+						continue
+					}
+					resolved := baseURL.ResolveReference(hrefURL)
+
+					// the fragment is no part of where the link leads, but
+					// anchors should still work offline
+					fragment := ""
+					if resolved.Fragment != "" && !c.StripFragments {
+						fragment = "#" + resolved.EscapedFragment()
+					}
+					resolved.Fragment, resolved.RawFragment = "", ""
+
+					if target, ok := c.lookupLocal(local, resolved); ok {
+						n.Attr[i].Val = c.localLink(p.path, target) + fragment
+					} else if resolved.Scheme == "http" || resolved.Scheme == "https" {
+						n.Attr[i].Val = resolved.String() + fragment
+					}
+				}
+			}
+		}
+
+		for child := n.FirstChild; child != nil; {
+			next := child.NextSibling
+			// links are local now, a <base> would send the browser online
+			if child.Type == html.ElementNode && child.Data == "base" {
+				n.RemoveChild(child)
+			} else {
+				f(child)
+			}
+			child = next
+		}
+	}
+	f(doc)
+
+	var buf bytes.Buffer
+	if err := html.Render(&buf, doc); err != nil {
+		return err
+	}
+
+	return c.store().Put(p.path, buf.Bytes(), ObjectMeta{URL: p.url.String(), ContentType: "text/html; charset=utf-8"})
+}
+
+// lookupLocal finds where the resource at u was saved, either as a page or
+// as an asset.
+func (c *Crawler) lookupLocal(local map[string]string, u *url.URL) (string, bool) {
+	if p, ok := local[c.pageKey(u)]; ok {
+		return p, true
+	}
+
+	asset := *u
+	asset.RawQuery = ""
+	asset.Fragment = ""
+	p, ok := local[asset.String()]
+
+	return p, ok
+}
+
+// localLink returns the link from the file at from to the local copy at
+// to: relative, or under BasePath when it is set.
+func (c *Crawler) localLink(from, to string) string {
+	if c.BasePath == "" {
+		return relativeLink(from, to)
+	}
+
+	rel, err := filepath.Rel(c.dir, to)
+	if err != nil {
+		return relativeLink(from, to)
+	}
+
+	return path.Join("/", c.BasePath, filepath.ToSlash(rel))
+}
+
+// relativeLink returns the link from the file at from to the file at to.
+func relativeLink(from, to string) string {
+	rel, err := filepath.Rel(filepath.Dir(from), to)
+	if err != nil {
+		return filepath.ToSlash(to)
+	}
+
+	return filepath.ToSlash(rel)
+}
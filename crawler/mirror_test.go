@@ -0,0 +1,258 @@
+package crawler
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestCrawler_Mirror(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/docs":
+			fmt.Fprint(w, `<a href="/docs/intro">intro</a><a href="https://other.example/">other</a><a href="/blog">blog</a>`)
+		case "/docs/intro":
+			fmt.Fprint(w, `<a href="/docs">back</a>`)
+		default:
+			http.NotFound(w, r)
+		}
+	}))
+	defer server.Close()
+
+	dir := t.TempDir()
+	c := New(server.URL+"/docs", dir)
+	c.Mirror = true
+	if err := c.Run(context.Background()); err != nil {
+		t.Fatalf("Run() error = %v", err)
+	}
+
+	tests := []struct {
+		name string
+		file string
+		want []string
+	}{
+		{
+			name: "Test crawled link points at local file",
+			file: filepath.Join(dir, "docs", "docs.html"),
+			want: []string{`href="intro/intro.html"`},
+		},
+		{
+			name: "Test uncrawled links stay absolute",
+			file: filepath.Join(dir, "docs", "docs.html"),
+			want: []string{`href="https://other.example/"`, `href="` + server.URL + `/blog"`},
+		},
+		{
+			name: "Test link to parent page",
+			file: filepath.Join(dir, "docs", "intro", "intro.html"),
+			want: []string{`href="../docs.html"`},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			data, err := os.ReadFile(tt.file)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			for _, want := range tt.want {
+				if !strings.Contains(string(data), want) {
+					t.Errorf("%v = %s, want it to contain %v", tt.file, data, want)
+				}
+			}
+		})
+	}
+}
+
+func TestCrawler_MirrorBasePath(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/docs":
+			fmt.Fprint(w, `<a href="/docs/intro#install">install</a><a href="/docs/intro">intro</a><a href="/blog">blog</a>`)
+		case "/docs/intro":
+			fmt.Fprint(w, `<a href="/docs">back</a>`)
+		default:
+			http.NotFound(w, r)
+		}
+	}))
+	defer server.Close()
+
+	tests := []struct {
+		name     string
+		basePath string
+		file     string
+		want     []string
+	}{
+		{
+			name:     "Test local links are prefixed with the base path",
+			basePath: "/archive/",
+			file:     filepath.Join("docs", "docs.html"),
+			want: []string{
+				`href="/archive/docs/intro/intro.html#install"`,
+				`href="/archive/docs/intro/intro.html"`,
+				`href="` + server.URL + `/blog"`,
+			},
+		},
+		{
+			name:     "Test base path without slashes",
+			basePath: "archive",
+			file:     filepath.Join("docs", "intro", "intro.html"),
+			want:     []string{`href="/archive/docs/docs.html"`},
+		},
+		{
+			name:     "Test root base path",
+			basePath: "/",
+			file:     filepath.Join("docs", "intro", "intro.html"),
+			want:     []string{`href="/docs/docs.html"`},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			dir := t.TempDir()
+			c := New(server.URL+"/docs", dir)
+			c.IgnoreRobots = true
+			c.Mirror = true
+			c.BasePath = tt.basePath
+			if err := c.Run(context.Background()); err != nil {
+				t.Fatalf("Run() error = %v", err)
+			}
+
+			file := filepath.Join(dir, tt.file)
+			data, err := os.ReadFile(file)
+			if err != nil {
+				t.Fatal(err)
+			}
+			for _, want := range tt.want {
+				if !strings.Contains(string(data), want) {
+					t.Errorf("%v = %s, want it to contain %v", file, data, want)
+				}
+			}
+		})
+	}
+}
+
+func TestCrawler_MirrorFragments(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/docs":
+			fmt.Fprint(w, `<a href="/docs/intro#install">install</a><a href="/docs#top">top</a><a href="#usage">usage</a><a href="https://other.example/page#faq">faq</a>`)
+		case "/docs/intro":
+			fmt.Fprint(w, `<h1 id="install">install</h1>`)
+		default:
+			http.NotFound(w, r)
+		}
+	}))
+	defer server.Close()
+
+	tests := []struct {
+		name           string
+		stripFragments bool
+		want           []string
+	}{
+		{
+			name: "Test fragments are kept in rewritten links",
+			want: []string{
+				`href="intro/intro.html#install"`,
+				`href="docs.html#top"`,
+				`href="#usage"`,
+				`href="https://other.example/page#faq"`,
+			},
+		},
+		{
+			name:           "Test fragments are stripped",
+			stripFragments: true,
+			want: []string{
+				`href="intro/intro.html"`,
+				`href="docs.html"`,
+				`href="#usage"`,
+				`href="https://other.example/page"`,
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			dir := t.TempDir()
+			c := New(server.URL+"/docs", dir)
+			c.IgnoreRobots = true
+			c.Mirror = true
+			c.StripFragments = tt.stripFragments
+			if err := c.Run(context.Background()); err != nil {
+				t.Fatalf("Run() error = %v", err)
+			}
+
+			// the anchored link is crawled once, under its plain url
+			if got := len(c.Records()); got != 2 {
+				t.Errorf("Run() crawled %v pages, want 2", got)
+			}
+
+			file := filepath.Join(dir, "docs", "docs.html")
+			data, err := os.ReadFile(file)
+			if err != nil {
+				t.Fatal(err)
+			}
+			for _, want := range tt.want {
+				if !strings.Contains(string(data), want) {
+					t.Errorf("%v = %s, want it to contain %v", file, data, want)
+				}
+			}
+		})
+	}
+}
+
+func TestCrawler_MirrorCaseInsensitivePaths(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch strings.ToLower(r.URL.Path) {
+		case "/docs":
+			fmt.Fprint(w, `<a href="/docs/Intro">intro</a><a href="/Docs/INTRO/">again</a>`)
+		case "/docs/intro":
+			fmt.Fprint(w, `<a href="/DOCS/">back</a>`)
+		default:
+			http.NotFound(w, r)
+		}
+	}))
+	defer server.Close()
+
+	dir := t.TempDir()
+	c := New(server.URL+"/docs", dir)
+	c.IgnoreRobots = true
+	c.Mirror = true
+	c.CaseInsensitivePaths = true
+	if err := c.Run(context.Background()); err != nil {
+		t.Fatalf("Run() error = %v", err)
+	}
+
+	tests := []struct {
+		name string
+		file string
+		want []string
+	}{
+		{
+			name: "Test mixed-case links point at the local file",
+			file: filepath.Join(dir, "docs", "docs.html"),
+			want: []string{`href="Intro/Intro.html">intro`, `href="Intro/Intro.html">again`},
+		},
+		{
+			name: "Test link back to the target with another case and a slash",
+			file: filepath.Join(dir, "docs", "Intro", "Intro.html"),
+			want: []string{`href="../docs.html"`},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			data, err := os.ReadFile(tt.file)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			for _, want := range tt.want {
+				if !strings.Contains(string(data), want) {
+					t.Errorf("%v = %s, want it to contain %v", tt.file, data, want)
+				}
+			}
+		})
+	}
+}
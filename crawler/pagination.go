@@ -0,0 +1,63 @@
+package crawler
+
+import (
+	"strings"
+	"sync"
+
+	"golang.org/x/net/html"
+)
+
+// nextRels are the rel values of the links FollowPagination follows out of
+// the path scope.
+var nextRels = map[string]bool{"next": true}
+
+// pagination tracks the chains of pages followed through rel="next" links,
+// with FollowPagination, so a link leading back into its own chain isn't
+// followed again.
+type pagination struct {
+	mutex sync.Mutex
+	prev  map[string]string
+}
+
+// follow records that the page from links to next as the page after it,
+// and reports whether next isn't already part of the chain leading to
+// from. Only the first page found linking to next is kept.
+func (p *pagination) follow(from, next string) bool {
+	p.mutex.Lock()
+	defer p.mutex.Unlock()
+
+	if p.prev == nil {
+		p.prev = map[string]string{}
+	}
+	for page := from; page != ""; page = p.prev[page] {
+		if page == next {
+			return false
+		}
+	}
+	if _, ok := p.prev[next]; !ok {
+		p.prev[next] = from
+	}
+
+	return true
+}
+
+// isNextLink reports whether an <a> or <link> element points to the next
+// page of a paginated list.
+func isNextLink(n *html.Node) bool {
+	return (n.Data == "a" || n.Data == "link") && hasRel(n, nextRels)
+}
+
+// hasRel reports whether n carries one of rels in its rel attribute.
+func hasRel(n *html.Node, rels map[string]bool) bool {
+	for _, a := range n.Attr {
+		if a.Key == "rel" {
+			for _, rel := range strings.Fields(strings.ToLower(a.Val)) {
+				if rels[rel] {
+					return true
+				}
+			}
+		}
+	}
+
+	return false
+}
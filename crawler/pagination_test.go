@@ -0,0 +1,105 @@
+package crawler
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"sort"
+	"strings"
+	"testing"
+)
+
+func TestCrawler_RunFollowPagination(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/blog/page/1":
+			fmt.Fprint(w, `<p>1</p><a rel="next" href="/blog/page/2">next</a><a href="/about">about</a>`)
+		case "/blog/page/2":
+			fmt.Fprint(w, `<p>2</p><link rel="next" href="/blog/page/3"><a href="/blog/page/2/post">post</a>`)
+		case "/blog/page/3":
+			// the last page wraps around to the first
+			fmt.Fprint(w, `<p>3</p><a rel="next" href="/blog/page/1">next</a>`)
+		case "/blog/page/2/post", "/about":
+			fmt.Fprint(w, `<p>page</p>`)
+		default:
+			http.NotFound(w, r)
+		}
+	}))
+	defer server.Close()
+
+	tests := []struct {
+		name   string
+		follow bool
+		want   []string
+	}{
+		{
+			name: "Test next pages out of the path scope are skipped by default",
+			want: []string{"/blog/page/1"},
+		},
+		{
+			name:   "Test next pages are followed with their children",
+			follow: true,
+			want:   []string{"/blog/page/1", "/blog/page/2", "/blog/page/2/post", "/blog/page/3"},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			c := New(server.URL+"/blog/page/1", t.TempDir())
+			c.IgnoreRobots = true
+			c.FollowPagination = tt.follow
+			if err := c.Run(context.Background()); err != nil {
+				t.Fatalf("Run() error = %v", err)
+			}
+
+			got := []string{}
+			for _, rec := range c.Records() {
+				got = append(got, strings.TrimPrefix(rec.URL, server.URL))
+			}
+			sort.Strings(got)
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("visited %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func Test_pagination_follow(t *testing.T) {
+	tests := []struct {
+		name  string
+		links [][2]string
+		want  []bool
+	}{
+		{
+			name:  "Test chain",
+			links: [][2]string{{"/1", "/2"}, {"/2", "/3"}},
+			want:  []bool{true, true},
+		},
+		{
+			name:  "Test link back to the start of the chain",
+			links: [][2]string{{"/1", "/2"}, {"/2", "/3"}, {"/3", "/1"}},
+			want:  []bool{true, true, false},
+		},
+		{
+			name:  "Test link to itself",
+			links: [][2]string{{"/1", "/1"}},
+			want:  []bool{false},
+		},
+		{
+			name:  "Test chains joining",
+			links: [][2]string{{"/1", "/3"}, {"/2", "/3"}, {"/3", "/2"}},
+			want:  []bool{true, true, true},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var p pagination
+			for i, link := range tt.links {
+				if got := p.follow(link[0], link[1]); got != tt.want[i] {
+					t.Errorf("follow(%v, %v) = %v, want %v", link[0], link[1], got, tt.want[i])
+				}
+			}
+		})
+	}
+}
@@ -0,0 +1,133 @@
+package crawler
+
+import (
+	"context"
+	"crypto/sha256"
+	"fmt"
+	"hash"
+	"io"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// partSuffix is appended to the name of an asset to name the part of it
+// downloaded so far, with ResumePartial.
+const partSuffix = ".part"
+
+// validatorSuffix is appended to the name of an asset to name the file
+// holding the If-Range validator of its part.
+const validatorSuffix = ".part.validator"
+
+// resumable reports whether a download streamed to dst may be resumed.
+// Parts can't be archived, so neither can they be with a WARC file.
+func (c *Crawler) resumable(dst string) bool {
+	return c.ResumePartial && dst != "" && c.warc == nil
+}
+
+// requestRange asks for the rest of the part of dst downloaded before, if
+// there is one and it can be validated, and returns its size. The body is
+// requested as is, since ranges are of the encoded bytes.
+func (c *Crawler) requestRange(req *http.Request, dst string) int64 {
+	req.Header.Set("Accept-Encoding", "identity")
+	req.Header.Del("Range")
+	req.Header.Del("If-Range")
+
+	info, err := os.Stat(dst + partSuffix)
+	if err != nil || info.Size() == 0 {
+		return 0
+	}
+	validator, err := os.ReadFile(dst + validatorSuffix)
+	if err != nil || len(validator) == 0 {
+		return 0
+	}
+
+	req.Header.Set("Range", fmt.Sprintf("bytes=%v-", info.Size()))
+	req.Header.Set("If-Range", string(validator))
+
+	return info.Size()
+}
+
+// rangeStart returns where the body of a 206 response starts in the
+// resource, from its Content-Range, or -1 when it doesn't say.
+func rangeStart(resp *http.Response) int64 {
+	var start, end int64
+	var size string
+	if _, err := fmt.Sscanf(resp.Header.Get("Content-Range"), "bytes %d-%d/%s", &start, &end, &size); err != nil {
+		return -1
+	}
+
+	return start
+}
+
+// rangeValidator returns the value that makes a later If-Range request of
+// the resource of resp safe: its strong ETag, or else its Last-Modified.
+// It is empty when the server doesn't serve byte ranges of it.
+func rangeValidator(resp *http.Response) string {
+	if resp.Header.Get("Accept-Ranges") != "bytes" || resp.Header.Get("Content-Encoding") != "" {
+		return ""
+	}
+
+	if etag := resp.Header.Get("ETag"); etag != "" && !strings.HasPrefix(etag, "W/") {
+		return etag
+	}
+
+	return resp.Header.Get("Last-Modified")
+}
+
+// streamPart is stream for a download that may be resumed: the body goes
+// to the part of fileName, after its first start bytes, and the part is
+// kept if the download fails and validator allows resuming it.
+func (c *Crawler) streamPart(ctx context.Context, url, fileName string, body io.Reader, r *response, start int64, validator string) error {
+	part, h, err := openPart(fileName, start, validator)
+	if err != nil {
+		return err
+	}
+
+	if err := c.streamTo(ctx, url, part, h, body, r, start); err != nil {
+		return err
+	}
+	os.Remove(fileName + validatorSuffix)
+
+	return nil
+}
+
+// openPart opens the part of fileName for writing after its first start
+// bytes, which are hashed, dropping the rest. With validator, it is saved
+// and the part marked to be kept on failure.
+func openPart(fileName string, start int64, validator string) (*tempFile, hash.Hash, error) {
+	if err := os.MkdirAll(filepath.Dir(fileName), os.ModePerm); err != nil {
+		return nil, nil, err
+	}
+
+	validatorName := fileName + validatorSuffix
+	if validator == "" {
+		os.Remove(validatorName)
+	} else if err := os.WriteFile(validatorName, []byte(validator), 0644); err != nil {
+		return nil, nil, err
+	}
+
+	f, err := os.OpenFile(fileName+partSuffix, os.O_RDWR|os.O_CREATE, 0644)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	h := sha256.New()
+	if _, err := io.CopyN(h, f, start); err != nil {
+		f.Close()
+		return nil, nil, err
+	}
+	if err := f.Truncate(start); err != nil {
+		f.Close()
+		return nil, nil, err
+	}
+
+	return &tempFile{File: f, target: fileName, partial: validator != ""}, h, nil
+}
+
+// removePart removes the part of fileName and its validator.
+func removePart(fileName string) {
+	os.Remove(fileName + partSuffix)
+	os.Remove(fileName + validatorSuffix)
+}
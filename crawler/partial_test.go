@@ -0,0 +1,138 @@
+package crawler
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+func TestCrawler_RunResumePartial(t *testing.T) {
+	content := bytes.Repeat([]byte("0123456789"), 1000)
+	changed := bytes.Repeat([]byte("abcdefghij"), 1000)
+
+	tests := []struct {
+		name      string
+		ranges    bool
+		etag      string
+		second    []byte
+		wantPart  bool
+		wantRange string
+	}{
+		{
+			name:      "Test interrupted download is resumed",
+			ranges:    true,
+			etag:      `"v1"`,
+			second:    content,
+			wantPart:  true,
+			wantRange: "bytes=5000-",
+		},
+		{
+			name:      "Test changed asset is downloaded again in full",
+			ranges:    true,
+			etag:      `"v2"`,
+			second:    changed,
+			wantPart:  true,
+			wantRange: "bytes=5000-",
+		},
+		{
+			name:   "Test server without ranges is downloaded again in full",
+			second: content,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var mutex sync.Mutex
+			run := 0
+			gotRange := ""
+			server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				switch r.URL.Path {
+				case "/docs":
+					fmt.Fprint(w, `<p><img src="/docs/big.bin"></p>`)
+				case "/docs/big.bin":
+					mutex.Lock()
+					first := run == 0
+					if !first {
+						gotRange = r.Header.Get("Range")
+					}
+					mutex.Unlock()
+
+					if first {
+						// half the body, then the connection drops
+						if tt.ranges {
+							w.Header().Set("Accept-Ranges", "bytes")
+							w.Header().Set("ETag", `"v1"`)
+						}
+						w.Header().Set("Content-Length", fmt.Sprint(len(content)))
+						w.Write(content[:len(content)/2])
+						w.(http.Flusher).Flush()
+						panic(http.ErrAbortHandler)
+					}
+
+					if tt.ranges {
+						w.Header().Set("ETag", tt.etag)
+						http.ServeContent(w, r, "big.bin", time.Time{}, bytes.NewReader(tt.second))
+						return
+					}
+					w.Write(tt.second)
+				default:
+					http.NotFound(w, r)
+				}
+			}))
+			defer server.Close()
+
+			dir := t.TempDir()
+			fileName := filepath.Join(dir, "docs", "big.bin")
+
+			c := New(server.URL+"/docs", dir)
+			c.IgnoreRobots = true
+			c.Assets = true
+			c.Retries = 0
+			c.ResumePartial = true
+			if err := c.Run(context.Background()); err == nil || !strings.Contains(err.Error(), "big.bin") {
+				t.Fatalf("Run() error = %v, want the interrupted asset", err)
+			}
+
+			_, err := os.Stat(fileName + partSuffix)
+			if gotPart := err == nil; gotPart != tt.wantPart {
+				t.Fatalf("part kept = %v, want %v", gotPart, tt.wantPart)
+			}
+
+			mutex.Lock()
+			run++
+			mutex.Unlock()
+
+			c = New(server.URL+"/docs", dir)
+			c.IgnoreRobots = true
+			c.Assets = true
+			c.Retries = 0
+			c.ResumePartial = true
+			if err := c.Run(context.Background()); err != nil {
+				t.Fatalf("Run() error = %v", err)
+			}
+
+			data, err := os.ReadFile(fileName)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !bytes.Equal(data, tt.second) {
+				t.Errorf("saved %v bytes starting with %q, want %q", len(data), data[:10], tt.second[:10])
+			}
+			if gotRange != tt.wantRange {
+				t.Errorf("Range = %q, want %q", gotRange, tt.wantRange)
+			}
+			for _, suffix := range []string{partSuffix, validatorSuffix} {
+				if _, err := os.Stat(fileName + suffix); err == nil {
+					t.Errorf("%v left behind", fileName+suffix)
+				}
+			}
+		})
+	}
+}
@@ -0,0 +1,68 @@
+package crawler
+
+import (
+	"context"
+	"math/rand"
+	"net/url"
+	"sync"
+	"time"
+)
+
+// hostLimiter spaces out requests to the same host. Hosts are tracked
+// independently so a slow host doesn't throttle the others.
+type hostLimiter struct {
+	mutex sync.Mutex
+	next  map[string]time.Time
+}
+
+// wait blocks until a request to host may be issued, reserving the next slot
+// delay later for whoever comes after.
+func (l *hostLimiter) wait(ctx context.Context, host string, delay time.Duration) error {
+	if delay <= 0 {
+		return nil
+	}
+
+	l.mutex.Lock()
+	if l.next == nil {
+		l.next = map[string]time.Time{}
+	}
+	now := time.Now()
+	at := l.next[host]
+	if at.Before(now) {
+		at = now
+	}
+	l.next[host] = at.Add(delay)
+	l.mutex.Unlock()
+
+	return sleep(ctx, time.Until(at))
+}
+
+// hostDelay returns the interval to keep between requests to u's host: the
+// larger of Delay, with Jitter applied, and the host's robots.txt
+// Crawl-delay.
+func (c *Crawler) hostDelay(u *url.URL) time.Duration {
+	delay := jitter(c.Delay, c.Jitter)
+
+	if !c.IgnoreRobots {
+		if v, ok := c.robots.Load(u.Host); ok {
+			if rules := v.(*robotsEntry).rules.Load(); rules != nil && rules.delay > delay {
+				delay = rules.delay
+			}
+		}
+	}
+
+	return delay
+}
+
+// jitter returns d moved by a random amount of up to fraction of it, either
+// way. fraction is kept between 0 and 1.
+func jitter(d time.Duration, fraction float64) time.Duration {
+	if d <= 0 || fraction <= 0 {
+		return d
+	}
+	if fraction > 1 {
+		fraction = 1
+	}
+
+	return time.Duration(float64(d) * (1 + fraction*(2*rand.Float64()-1)))
+}
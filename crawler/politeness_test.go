@@ -0,0 +1,56 @@
+package crawler
+
+import (
+	"testing"
+	"time"
+)
+
+func Test_jitter(t *testing.T) {
+	tests := []struct {
+		name     string
+		delay    time.Duration
+		fraction float64
+		wantMin  time.Duration
+		wantMax  time.Duration
+	}{
+		{
+			name:    "Test no jitter keeps the delay",
+			delay:   time.Second,
+			wantMin: time.Second,
+			wantMax: time.Second,
+		},
+		{
+			name:     "Test no delay stays none",
+			fraction: 0.5,
+		},
+		{
+			name:     "Test delay varies within the fraction",
+			delay:    time.Second,
+			fraction: 0.5,
+			wantMin:  500 * time.Millisecond,
+			wantMax:  1500 * time.Millisecond,
+		},
+		{
+			name:     "Test fraction is capped",
+			delay:    time.Second,
+			fraction: 3,
+			wantMin:  0,
+			wantMax:  2 * time.Second,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			seen := map[time.Duration]bool{}
+			for i := 0; i < 100; i++ {
+				got := jitter(tt.delay, tt.fraction)
+				if got < tt.wantMin || got > tt.wantMax {
+					t.Fatalf("jitter() = %v, want between %v and %v", got, tt.wantMin, tt.wantMax)
+				}
+				seen[got] = true
+			}
+			if tt.wantMin != tt.wantMax && len(seen) == 1 {
+				t.Errorf("jitter() always returned the same delay")
+			}
+		})
+	}
+}
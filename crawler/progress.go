@@ -0,0 +1,82 @@
+package crawler
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"time"
+)
+
+// progressInterval is how often a progress line is written while crawling.
+const progressInterval = 2 * time.Second
+
+// isTerminal reports whether w is a terminal, where a progress line can be
+// redrawn in place.
+func isTerminal(w io.Writer) bool {
+	f, ok := w.(*os.File)
+	if !ok {
+		return false
+	}
+	info, err := f.Stat()
+
+	return err == nil && info.Mode()&os.ModeCharDevice != 0
+}
+
+// reportProgress writes a progress line to w every interval until the
+// returned function is called. On a terminal the line is redrawn in place,
+// elsewhere a new one is written each time.
+func (c *Crawler) reportProgress(w io.Writer, interval time.Duration) func() {
+	done := make(chan struct{})
+	stopped := make(chan struct{})
+	tty := isTerminal(w)
+	drawn := false
+
+	go func() {
+		defer close(stopped)
+
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+
+		last, lastBytes := time.Now(), c.stats.bytes.Load()
+		for {
+			select {
+			case now := <-ticker.C:
+				bytes := c.stats.bytes.Load()
+				rate := float64(bytes-lastBytes) / now.Sub(last).Seconds()
+				last, lastBytes = now, bytes
+
+				line := fmt.Sprintf("pages: %v, assets: %v, queued: %v, active: %v, rate: %v/s",
+					c.stats.pages.Load(), c.stats.assets.Load(), c.queue.len(), c.stats.active.Load(), formatBytes(rate))
+				if tty {
+					fmt.Fprintf(w, "\r\033[K%v", line)
+					drawn = true
+				} else {
+					fmt.Fprintln(w, line)
+				}
+			case <-done:
+				// leave the cursor on a fresh line for what follows
+				if drawn {
+					fmt.Fprintln(w)
+				}
+				return
+			}
+		}
+	}()
+
+	return func() {
+		close(done)
+		<-stopped
+	}
+}
+
+// formatBytes returns n bytes in the largest unit that keeps it above 1.
+func formatBytes(n float64) string {
+	units := []string{"B", "KiB", "MiB", "GiB"}
+	i := 0
+	for n >= 1024 && i < len(units)-1 {
+		n /= 1024
+		i++
+	}
+
+	return fmt.Sprintf("%.1f %v", n, units[i])
+}
@@ -0,0 +1,66 @@
+package crawler
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestCrawler_reportProgress(t *testing.T) {
+	c := New("http://example.com", t.TempDir())
+	c.stats.pages.Store(3)
+	c.stats.assets.Store(2)
+	c.stats.active.Store(1)
+	c.queue.push(job{url: "http://example.com/next"})
+
+	var buf bytes.Buffer
+	stop := c.reportProgress(&buf, 10*time.Millisecond)
+	time.Sleep(35 * time.Millisecond)
+	stop()
+
+	lines := strings.Split(strings.TrimSuffix(buf.String(), "\n"), "\n")
+	if len(lines) < 2 {
+		t.Fatalf("reportProgress() wrote %q, want a line per tick", buf.String())
+	}
+	want := "pages: 3, assets: 2, queued: 1, active: 1, rate: 0.0 B/s"
+	for _, line := range lines {
+		if line != want {
+			t.Errorf("reportProgress() wrote %q, want %q", line, want)
+		}
+	}
+	if strings.Contains(buf.String(), "\r") {
+		t.Errorf("reportProgress() redrew the line on a non-terminal: %q", buf.String())
+	}
+}
+
+func Test_formatBytes(t *testing.T) {
+	tests := []struct {
+		name string
+		n    float64
+		want string
+	}{
+		{
+			name: "Test bytes",
+			n:    512,
+			want: "512.0 B",
+		},
+		{
+			name: "Test kibibytes",
+			n:    1536,
+			want: "1.5 KiB",
+		},
+		{
+			name: "Test largest unit",
+			n:    2 << 40,
+			want: "2048.0 GiB",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := formatBytes(tt.n); got != tt.want {
+				t.Errorf("formatBytes() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,84 @@
+package crawler
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/url"
+	"sync/atomic"
+)
+
+// ProxyStats counts the requests sent through a proxy.
+type ProxyStats struct {
+	OK     int64 `json:"ok"`
+	Failed int64 `json:"failed"`
+}
+
+// proxyKey is the request context key of the proxy chosen for it.
+type proxyKey struct{}
+
+// proxyRotator is a RoundTripper sending each request through the next
+// proxy of its list, in turn, and counting how each one fares.
+type proxyRotator struct {
+	base    *http.Transport
+	proxies []*url.URL
+	next    atomic.Uint64
+	ok      []atomic.Int64
+	failed  []atomic.Int64
+}
+
+// newProxyRotator parses proxies and makes base route its requests through
+// them.
+func newProxyRotator(base *http.Transport, proxies []string) (*proxyRotator, error) {
+	r := &proxyRotator{base: base}
+	for _, p := range proxies {
+		u, err := url.Parse(p)
+		if err != nil {
+			return nil, fmt.Errorf("invalid proxy: %w", err)
+		}
+		switch u.Scheme {
+		case "http", "https", "socks5", "socks5h":
+		default:
+			return nil, fmt.Errorf("invalid proxy: unsupported scheme %q", u.Scheme)
+		}
+		r.proxies = append(r.proxies, u)
+	}
+	r.ok = make([]atomic.Int64, len(r.proxies))
+	r.failed = make([]atomic.Int64, len(r.proxies))
+
+	// credentials in the url are used for both HTTP and SOCKS5 proxies
+	base.Proxy = func(req *http.Request) (*url.URL, error) {
+		i, ok := req.Context().Value(proxyKey{}).(int)
+		if !ok {
+			return nil, nil
+		}
+		return r.proxies[i], nil
+	}
+
+	return r, nil
+}
+
+func (r *proxyRotator) RoundTrip(req *http.Request) (*http.Response, error) {
+	i := int((r.next.Add(1) - 1) % uint64(len(r.proxies)))
+	req = req.WithContext(context.WithValue(req.Context(), proxyKey{}, i))
+
+	resp, err := r.base.RoundTrip(req)
+	if err != nil || resp.StatusCode == http.StatusProxyAuthRequired {
+		r.failed[i].Add(1)
+	} else {
+		r.ok[i].Add(1)
+	}
+
+	return resp, err
+}
+
+// stats returns the counts of every proxy, keyed by its url without the
+// password.
+func (r *proxyRotator) stats() map[string]ProxyStats {
+	st := map[string]ProxyStats{}
+	for i, u := range r.proxies {
+		st[u.Redacted()] = ProxyStats{OK: r.ok[i].Load(), Failed: r.failed[i].Load()}
+	}
+
+	return st
+}
@@ -0,0 +1,193 @@
+package crawler
+
+import (
+	"bufio"
+	"context"
+	"encoding/binary"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"strconv"
+	"testing"
+)
+
+func TestCrawler_downloadProxies(t *testing.T) {
+	newProxy := func(name string) *httptest.Server {
+		return httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			fmt.Fprintf(w, `<p>%v</p>`, name)
+		}))
+	}
+	first, second := newProxy("first"), newProxy("second")
+	defer first.Close()
+	defer second.Close()
+
+	// nothing listens there anymore
+	dead := newProxy("dead")
+	dead.Close()
+
+	tests := []struct {
+		name      string
+		proxies   []string
+		downloads int
+		want      map[string]ProxyStats
+	}{
+		{
+			name:      "Test requests rotate through the proxies",
+			proxies:   []string{first.URL, second.URL, dead.URL},
+			downloads: 6,
+			want: map[string]ProxyStats{
+				first.URL:  {OK: 2},
+				second.URL: {OK: 2},
+				dead.URL:   {Failed: 2},
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			c := New("http://example.invalid", t.TempDir())
+			c.Proxies = tt.proxies
+			c.Retries = 0
+			client, err := c.newClient()
+			if err != nil {
+				t.Fatal(err)
+			}
+			c.client = client
+
+			for i := 0; i < tt.downloads; i++ {
+				c.download(context.Background(), "http://example.invalid/page")
+			}
+
+			if got := c.Stats().Proxies; !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("Stats().Proxies = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestCrawler_downloadSocks5Auth(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprint(w, `<p>page</p>`)
+	}))
+	defer server.Close()
+
+	proxy := socks5Server(t, "user", "secret")
+
+	tests := []struct {
+		name    string
+		proxy   string
+		wantErr bool
+	}{
+		{
+			name:  "Test credentials are sent to the proxy",
+			proxy: "socks5://user:secret@" + proxy,
+		},
+		{
+			name:    "Test wrong credentials are refused",
+			proxy:   "socks5://user:wrong@" + proxy,
+			wantErr: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			c := New(server.URL, t.TempDir())
+			c.Proxy = tt.proxy
+			c.Retries = 0
+			client, err := c.newClient()
+			if err != nil {
+				t.Fatal(err)
+			}
+			c.client = client
+
+			_, err = c.download(context.Background(), server.URL+"/page")
+			if (err != nil) != tt.wantErr {
+				t.Errorf("download() error = %v, wantErr %v", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+// socks5Server starts a SOCKS5 proxy accepting only user and pass, and
+// returns its address.
+func socks5Server(t *testing.T, user, pass string) string {
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { l.Close() })
+
+	go func() {
+		for {
+			conn, err := l.Accept()
+			if err != nil {
+				return
+			}
+			go serveSocks5(conn, user, pass)
+		}
+	}()
+
+	return l.Addr().String()
+}
+
+func serveSocks5(conn net.Conn, user, pass string) {
+	defer conn.Close()
+	r := bufio.NewReader(conn)
+
+	// greeting: version and auth methods, we only take username/password
+	head := make([]byte, 2)
+	if _, err := io.ReadFull(r, head); err != nil {
+		return
+	}
+	if _, err := io.ReadFull(r, make([]byte, head[1])); err != nil {
+		return
+	}
+	conn.Write([]byte{5, 2})
+
+	// username/password sub-negotiation
+	readField := func() string {
+		n, _ := r.ReadByte()
+		b := make([]byte, n)
+		io.ReadFull(r, b)
+		return string(b)
+	}
+	r.ReadByte()
+	if readField() != user || readField() != pass {
+		conn.Write([]byte{1, 1})
+		return
+	}
+	conn.Write([]byte{1, 0})
+
+	// connect request
+	req := make([]byte, 4)
+	if _, err := io.ReadFull(r, req); err != nil {
+		return
+	}
+	var host string
+	switch req[3] {
+	case 1:
+		ip := make([]byte, 4)
+		io.ReadFull(r, ip)
+		host = net.IP(ip).String()
+	case 3:
+		host = readField()
+	case 4:
+		ip := make([]byte, 16)
+		io.ReadFull(r, ip)
+		host = net.IP(ip).String()
+	}
+	port := make([]byte, 2)
+	io.ReadFull(r, port)
+
+	target, err := net.Dial("tcp", net.JoinHostPort(host, strconv.Itoa(int(binary.BigEndian.Uint16(port)))))
+	if err != nil {
+		conn.Write([]byte{5, 5, 0, 1, 0, 0, 0, 0, 0, 0})
+		return
+	}
+	defer target.Close()
+	conn.Write([]byte{5, 0, 0, 1, 0, 0, 0, 0, 0, 0})
+
+	go io.Copy(target, r)
+	io.Copy(conn, target)
+}
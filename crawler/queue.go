@@ -0,0 +1,185 @@
+package crawler
+
+import (
+	"context"
+	"sync"
+)
+
+// The crawl orders a Crawler supports.
+const (
+	StrategyBFS = "bfs"
+	StrategyDFS = "dfs"
+)
+
+// job is a page or asset waiting to be crawled, or an off-site link to
+// check, found on the page from.
+type job struct {
+	url      string
+	depth    int
+	asset    bool
+	external bool
+	from     string
+}
+
+// frontier holds the jobs not handed to a worker yet. It is a queue for
+// breadth-first crawls and a stack for depth-first ones.
+type frontier struct {
+	mutex sync.Mutex
+	jobs  []job
+	lifo  bool
+
+	// ready is signalled whenever a job is pushed
+	ready chan struct{}
+}
+
+func (f *frontier) push(j job) {
+	f.mutex.Lock()
+	f.jobs = append(f.jobs, j)
+	f.mutex.Unlock()
+
+	select {
+	case f.ready <- struct{}{}:
+	default:
+	}
+}
+
+// reset empties the frontier for a new crawl. It is done in place, under
+// the mutex, as the metrics may be read from it at any time.
+func (f *frontier) reset(lifo bool) {
+	f.mutex.Lock()
+	defer f.mutex.Unlock()
+
+	f.jobs = nil
+	f.lifo = lifo
+	if f.ready == nil {
+		f.ready = make(chan struct{}, 1)
+	}
+}
+
+// len returns the number of jobs waiting.
+func (f *frontier) len() int {
+	f.mutex.Lock()
+	defer f.mutex.Unlock()
+
+	return len(f.jobs)
+}
+
+func (f *frontier) pop() (job, bool) {
+	f.mutex.Lock()
+	defer f.mutex.Unlock()
+
+	if len(f.jobs) == 0 {
+		return job{}, false
+	}
+
+	var j job
+	if f.lifo {
+		j = f.jobs[len(f.jobs)-1]
+		f.jobs = f.jobs[:len(f.jobs)-1]
+	} else {
+		j = f.jobs[0]
+		f.jobs = f.jobs[1:]
+	}
+
+	return j, true
+}
+
+// enqueue adds jobs to the frontier. Depth-first crawls push them in
+// reverse so the first link on a page is the first one popped. The wait
+// group counts every job, queued or running, so the crawl is over when it
+// drops to zero.
+func (c *Crawler) enqueue(jobs ...job) {
+	for i := range jobs {
+		j := jobs[i]
+		if c.queue.lifo {
+			j = jobs[len(jobs)-1-i]
+		}
+
+		if _, ok := c.pending.Load(j.url); !ok {
+			c.pending.Store(j.url, pendingURL{URL: j.url, Depth: j.depth, Asset: j.asset, External: j.external, From: j.from})
+		}
+
+		if !j.external {
+			c.prefetchRobots(j.url)
+		}
+
+		c.wg.Add(1)
+		c.queue.push(j)
+		c.emit(Event{Kind: EventQueued, URL: j.url, Depth: j.depth, Asset: j.asset})
+	}
+}
+
+// work feeds the frontier to a pool of concurrency workers, in the order of
+// the crawl strategy, until every job is done.
+func (c *Crawler) work(ctx context.Context, concurrency int) {
+	jobs := make(chan job)
+
+	// a job is only popped once a worker is free for it, so jobs pushed in
+	// the meantime can still go first
+	free := make(chan struct{}, concurrency)
+	for i := 0; i < concurrency; i++ {
+		free <- struct{}{}
+		go func() {
+			for j := range jobs {
+				c.stats.active.Add(1)
+				if err := c.process(ctx, j); err != nil {
+					c.fail(err)
+				}
+				c.stats.active.Add(-1)
+				free <- struct{}{}
+				c.wg.Done()
+			}
+		}()
+	}
+
+	// jobs are only added by running ones, so once the count drops to zero
+	// it stays there
+	idle := make(chan struct{})
+	go func() {
+		c.wg.Wait()
+		close(idle)
+	}()
+
+	defer close(jobs)
+	for {
+		select {
+		case <-free:
+		case <-idle:
+			return
+		case <-ctx.Done():
+			c.drop(idle)
+			return
+		}
+
+		j, ok := c.queue.pop()
+		for !ok {
+			select {
+			case <-c.queue.ready:
+			case <-idle:
+				return
+			case <-ctx.Done():
+				c.drop(idle)
+				return
+			}
+			j, ok = c.queue.pop()
+		}
+
+		jobs <- j
+	}
+}
+
+// drop discards the queued jobs, and those the running ones still add,
+// until the running ones are over. They stay pending in the crawl state.
+func (c *Crawler) drop(idle <-chan struct{}) {
+	for {
+		for _, ok := c.queue.pop(); ok; _, ok = c.queue.pop() {
+			c.wg.Done()
+		}
+
+		select {
+		case <-c.queue.ready:
+		case <-idle:
+			return
+		}
+	}
+}
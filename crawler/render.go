@@ -0,0 +1,74 @@
+package crawler
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"net/url"
+	"os/exec"
+
+	"golang.org/x/net/html"
+)
+
+// Renderer returns the HTML of a page once its scripts have run, so links
+// added by JavaScript can be found. body is the page as downloaded.
+type Renderer interface {
+	Render(ctx context.Context, url string, body []byte) ([]byte, error)
+}
+
+// NopRenderer is the default Renderer: pages are used as downloaded, which
+// is the fastest.
+type NopRenderer struct{}
+
+func (NopRenderer) Render(_ context.Context, _ string, body []byte) ([]byte, error) {
+	return body, nil
+}
+
+// CommandRenderer renders pages with an external program, typically a
+// headless browser such as
+// "chromium --headless --disable-gpu --dump-dom". It is run with the page
+// url as its last argument and prints the rendered HTML.
+type CommandRenderer struct {
+	Command []string
+}
+
+func (r CommandRenderer) Render(ctx context.Context, url string, _ []byte) ([]byte, error) {
+	if len(r.Command) == 0 {
+		return nil, errors.New("no render command")
+	}
+
+	var stderr bytes.Buffer
+	cmd := exec.CommandContext(ctx, r.Command[0], append(r.Command[1:], url)...)
+	cmd.Stderr = &stderr
+	out, err := cmd.Output()
+	if err != nil {
+		return nil, fmt.Errorf("%v: %w: %s", r.Command[0], err, bytes.TrimSpace(stderr.Bytes()))
+	}
+
+	return out, nil
+}
+
+// renderedDoc returns the document the links of the page at pageURL are
+// extracted from: doc, parsed from content, unless a Renderer other than
+// NopRenderer is set. Pages it fails to render fall back to doc.
+func (c *Crawler) renderedDoc(ctx context.Context, pageURL *url.URL, content []byte, doc *html.Node) *html.Node {
+	if _, nop := c.Renderer.(NopRenderer); nop || c.Renderer == nil {
+		return doc
+	}
+
+	c.Logger.Debug("rendering", "url", pageURL.String())
+	rendered, err := c.Renderer.Render(ctx, pageURL.String(), content)
+	if err != nil {
+		c.Logger.Warn("error rendering the page, using it as downloaded", "url", pageURL.String(), "err", err)
+		return doc
+	}
+
+	renderedDoc, err := parseHTML(rendered)
+	if err != nil {
+		c.Logger.Warn("error parsing the rendered page, using it as downloaded", "url", pageURL.String(), "err", err)
+		return doc
+	}
+
+	return renderedDoc
+}
@@ -0,0 +1,127 @@
+package crawler
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"reflect"
+	"testing"
+)
+
+// renderFunc is a Renderer calling itself.
+type renderFunc func(ctx context.Context, url string, body []byte) ([]byte, error)
+
+func (f renderFunc) Render(ctx context.Context, url string, body []byte) ([]byte, error) {
+	return f(ctx, url, body)
+}
+
+func TestCrawler_RunRenderer(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/app":
+			fmt.Fprint(w, `<div id="root"></div><script>render()</script>`)
+		case "/app/page":
+			fmt.Fprint(w, `<p>page</p>`)
+		default:
+			http.NotFound(w, r)
+		}
+	}))
+	defer server.Close()
+
+	tests := []struct {
+		name        string
+		renderer    Renderer
+		wantVisited []string
+	}{
+		{
+			name:        "Test static html has no links",
+			renderer:    NopRenderer{},
+			wantVisited: []string{server.URL + "/app"},
+		},
+		{
+			name: "Test links of the rendered page are followed",
+			renderer: renderFunc(func(_ context.Context, url string, body []byte) ([]byte, error) {
+				return []byte(`<div id="root"><a href="/app/page">page</a></div>`), nil
+			}),
+			wantVisited: []string{server.URL + "/app", server.URL + "/app/page"},
+		},
+		{
+			name: "Test failed render falls back to the static html",
+			renderer: renderFunc(func(_ context.Context, url string, body []byte) ([]byte, error) {
+				return nil, errors.New("no browser")
+			}),
+			wantVisited: []string{server.URL + "/app"},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			c := New(server.URL+"/app", t.TempDir())
+			c.IgnoreRobots = true
+			c.Renderer = tt.renderer
+			if err := c.Run(context.Background()); err != nil {
+				t.Fatalf("Run() error = %v", err)
+			}
+
+			got := []string{}
+			for _, rec := range c.Records() {
+				got = append(got, rec.URL)
+			}
+			if !reflect.DeepEqual(got, tt.wantVisited) {
+				t.Errorf("Run() visited %v, want %v", got, tt.wantVisited)
+			}
+		})
+	}
+}
+
+func TestCommandRenderer_Render(t *testing.T) {
+	// the test binary stands in for a browser, see TestMain
+	tests := []struct {
+		name    string
+		mode    string
+		want    string
+		wantErr bool
+	}{
+		{
+			name: "Test output is the rendered page",
+			mode: "ok",
+			want: `<a href="https://example.com/page">rendered</a>`,
+		},
+		{
+			name:    "Test failing command is an error",
+			mode:    "fail",
+			wantErr: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Setenv("CRAWLER_RENDER_HELPER", tt.mode)
+			r := CommandRenderer{Command: []string{os.Args[0], "-test.run=^$"}}
+
+			got, err := r.Render(context.Background(), "https://example.com/page", nil)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("Render() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if string(got) != tt.want {
+				t.Errorf("Render() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+// TestMain makes the test binary act as a render command when
+// CRAWLER_RENDER_HELPER is set: it prints a link to the url it is given.
+func TestMain(m *testing.M) {
+	switch os.Getenv("CRAWLER_RENDER_HELPER") {
+	case "ok":
+		fmt.Printf(`<a href="%v">rendered</a>`, os.Args[len(os.Args)-1])
+		os.Exit(0)
+	case "fail":
+		fmt.Fprint(os.Stderr, "no display")
+		os.Exit(1)
+	}
+
+	os.Exit(m.Run())
+}
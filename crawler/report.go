@@ -0,0 +1,120 @@
+package crawler
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/url"
+	"os"
+	"sort"
+)
+
+// Record describes the outcome of crawling a single URL.
+type Record struct {
+	URL           string `json:"url"`
+	FinalURL      string `json:"final_url,omitempty"`
+	Canonical     string `json:"canonical,omitempty"`
+	StatusCode    int    `json:"status_code,omitempty"`
+	ContentLength int    `json:"content_length"`
+	ContentType   string `json:"content_type,omitempty"`
+	ContentHash   string `json:"content_hash,omitempty"`
+	Title         string `json:"title,omitempty"`
+	LastModified  string `json:"last_modified,omitempty"`
+	Path          string `json:"path,omitempty"`
+	Depth         int    `json:"depth"`
+	Cached        bool   `json:"cached"`
+	Asset         bool   `json:"asset,omitempty"`
+	NoIndex       bool   `json:"noindex,omitempty"`
+	Soft404       bool   `json:"soft_404,omitempty"`
+	External      bool   `json:"external,omitempty"`
+	LinkedFrom    string `json:"linked_from,omitempty"`
+	Error         string `json:"error,omitempty"`
+	OnPageError   string `json:"on_page_error,omitempty"`
+}
+
+func (c *Crawler) addRecord(rec Record) {
+	c.stats.count(rec)
+
+	c.recordsMutex.Lock()
+	defer c.recordsMutex.Unlock()
+
+	c.records = append(c.records, rec)
+}
+
+// Records returns a copy of the records collected so far, sorted by URL.
+func (c *Crawler) Records() []Record {
+	c.recordsMutex.Lock()
+	records := make([]Record, len(c.records))
+	copy(records, c.records)
+	c.recordsMutex.Unlock()
+
+	sort.Slice(records, func(i, j int) bool {
+		return records[i].URL < records[j].URL
+	})
+
+	return records
+}
+
+// ReadReport reads the records of a report written by WriteReport.
+func ReadReport(fileName string) ([]Record, error) {
+	data, err := os.ReadFile(fileName)
+	if err != nil {
+		return nil, err
+	}
+
+	var records []Record
+	if err := json.Unmarshal(data, &records); err != nil {
+		return nil, fmt.Errorf("error reading the report %v: %w", fileName, err)
+	}
+
+	return records, nil
+}
+
+// resumeRecords marks the urls ResumeRecords got as visited and returns
+// the jobs retrying the others, at the depth they failed at so MaxDepth
+// still holds.
+func (c *Crawler) resumeRecords() []job {
+	jobs := []job{}
+	for _, rec := range c.ResumeRecords {
+		if rec.Error != "" {
+			jobs = append(jobs, job{url: rec.URL, depth: rec.Depth, asset: rec.Asset, external: rec.External, from: rec.LinkedFrom})
+			continue
+		}
+		if rec.External {
+			continue
+		}
+
+		for _, u := range []string{rec.URL, rec.FinalURL} {
+			if u != "" {
+				c.visited.Store(c.visitedKey(u), struct{}{})
+			}
+		}
+	}
+
+	if len(c.ResumeRecords) > 0 {
+		c.Logger.Info("retrying the failed urls of the report", "records", len(c.ResumeRecords), "retried", len(jobs))
+	}
+
+	return jobs
+}
+
+// visitedKey returns the key of the visited set for a url a Record or the
+// state file reports.
+func (c *Crawler) visitedKey(raw string) string {
+	u, err := url.Parse(raw)
+	if err != nil {
+		return raw
+	}
+
+	return c.pageKey(u)
+}
+
+// WriteReport writes the records collected so far to fileName as a JSON
+// array. Failed pages are included with their Error set.
+func (c *Crawler) WriteReport(fileName string) error {
+	data, err := json.MarshalIndent(c.Records(), "", "  ")
+	if err != nil {
+		return err
+	}
+
+	return os.WriteFile(fileName, data, 0644)
+}
@@ -0,0 +1,154 @@
+package crawler
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"reflect"
+	"sort"
+	"strings"
+	"sync"
+	"testing"
+)
+
+func TestCrawler_RunResumeRecords(t *testing.T) {
+	var mutex sync.Mutex
+	run := 0
+	requests := []string{}
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		mutex.Lock()
+		first := run == 0
+		if !first {
+			requests = append(requests, r.URL.Path)
+		}
+		mutex.Unlock()
+
+		switch r.URL.Path {
+		case "/docs":
+			fmt.Fprint(w, `<a href="/docs/a">a</a><a href="/docs/flaky">flaky</a><a href="/docs/missing">missing</a>`)
+		case "/docs/a":
+			fmt.Fprint(w, `<p>a</p>`)
+		case "/docs/flaky":
+			if first {
+				http.Error(w, "busy", http.StatusServiceUnavailable)
+				return
+			}
+			fmt.Fprint(w, `<a href="/docs/flaky/b">b</a>`)
+		case "/docs/flaky/b":
+			fmt.Fprint(w, `<p>b</p>`)
+		default:
+			http.NotFound(w, r)
+		}
+	}))
+	defer server.Close()
+
+	dir := t.TempDir()
+	report := filepath.Join(t.TempDir(), "report.json")
+	c := New(server.URL+"/docs", dir)
+	c.IgnoreRobots = true
+	c.Retries = 0
+	c.Run(context.Background())
+	if err := c.WriteReport(report); err != nil {
+		t.Fatal(err)
+	}
+
+	mutex.Lock()
+	run++
+	mutex.Unlock()
+
+	records, err := ReadReport(report)
+	if err != nil {
+		t.Fatalf("ReadReport() error = %v", err)
+	}
+	c = New(server.URL+"/docs", dir)
+	c.IgnoreRobots = true
+	c.Retries = 0
+	c.ResumeRecords = records
+	c.Run(context.Background())
+
+	sort.Strings(requests)
+	if want := []string{"/docs/flaky", "/docs/flaky/b", "/docs/missing"}; !reflect.DeepEqual(requests, want) {
+		t.Errorf("requests = %v, want %v", requests, want)
+	}
+
+	got := []string{}
+	for _, rec := range c.Records() {
+		got = append(got, fmt.Sprintf("%v %v", strings.TrimPrefix(rec.URL, server.URL), rec.StatusCode))
+	}
+	if want := []string{"/docs/flaky 200", "/docs/flaky/b 200", "/docs/missing 404"}; !reflect.DeepEqual(got, want) {
+		t.Errorf("Records() = %v, want %v", got, want)
+	}
+}
+
+func TestCrawler_RunResumeRecordsMaxDepth(t *testing.T) {
+	var mutex sync.Mutex
+	run := 0
+	requests := []string{}
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		mutex.Lock()
+		first := run == 0
+		if !first {
+			requests = append(requests, r.URL.Path)
+		}
+		mutex.Unlock()
+
+		switch r.URL.Path {
+		case "/docs":
+			fmt.Fprint(w, `<a href="/docs/flaky">flaky</a>`)
+		case "/docs/flaky":
+			if first {
+				http.Error(w, "busy", http.StatusServiceUnavailable)
+				return
+			}
+			fmt.Fprint(w, `<a href="/docs/flaky/b">b</a>`)
+		case "/docs/flaky/b":
+			fmt.Fprint(w, `<a href="/docs/flaky/b/c">c</a>`)
+		case "/docs/flaky/b/c":
+			fmt.Fprint(w, `<p>c</p>`)
+		default:
+			http.NotFound(w, r)
+		}
+	}))
+	defer server.Close()
+
+	dir := t.TempDir()
+	report := filepath.Join(t.TempDir(), "report.json")
+	c := New(server.URL+"/docs", dir)
+	c.IgnoreRobots = true
+	c.Retries = 0
+	c.MaxDepth = 2
+	c.Run(context.Background())
+	if err := c.WriteReport(report); err != nil {
+		t.Fatal(err)
+	}
+
+	mutex.Lock()
+	run++
+	mutex.Unlock()
+
+	records, err := ReadReport(report)
+	if err != nil {
+		t.Fatalf("ReadReport() error = %v", err)
+	}
+	c = New(server.URL+"/docs", dir)
+	c.IgnoreRobots = true
+	c.Retries = 0
+	c.MaxDepth = 2
+	c.ResumeRecords = records
+	c.Run(context.Background())
+
+	sort.Strings(requests)
+	if want := []string{"/docs/flaky", "/docs/flaky/b"}; !reflect.DeepEqual(requests, want) {
+		t.Errorf("requests = %v, want %v", requests, want)
+	}
+
+	got := []string{}
+	for _, rec := range c.Records() {
+		got = append(got, fmt.Sprintf("%v %v", strings.TrimPrefix(rec.URL, server.URL), rec.Depth))
+	}
+	if want := []string{"/docs/flaky 1", "/docs/flaky/b 2"}; !reflect.DeepEqual(got, want) {
+		t.Errorf("Records() = %v, want %v", got, want)
+	}
+}
@@ -0,0 +1,107 @@
+package crawler
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math/rand"
+	"net/http"
+	"strconv"
+	"time"
+)
+
+const (
+	// baseBackoff is the wait before the first retry. It doubles with every
+	// attempt up to maxBackoff.
+	baseBackoff = 500 * time.Millisecond
+	maxBackoff  = 30 * time.Second
+
+	// maxRetryAfter caps how long a server can ask us to wait.
+	maxRetryAfter = 2 * time.Minute
+)
+
+// StatusError is returned when the server answers with a status other than
+// 200 OK.
+type StatusError struct {
+	Code int
+}
+
+func (e *StatusError) Error() string {
+	return fmt.Sprintf("invalid status code %v", e.Code)
+}
+
+// retryable reports whether a failed attempt is worth repeating: connection
+// errors, timeouts and the statuses given, or 5xx and 429 when there are
+// none, are; other statuses, redirect loops, oversized bodies, hosts down,
+// cancellation and an unusable output dir aren't.
+func retryable(err error, statuses []int) bool {
+	if errors.Is(err, context.Canceled) || errors.Is(err, errTooManyRedirects) || errors.Is(err, errRedirectLoop) || errors.Is(err, ErrTooLarge) || errors.Is(err, ErrHostDown) || isFatal(err) {
+		return false
+	}
+
+	var statusErr *StatusError
+	if errors.As(err, &statusErr) {
+		if len(statuses) == 0 {
+			return statusErr.Code == http.StatusTooManyRequests || statusErr.Code >= 500
+		}
+		for _, code := range statuses {
+			if statusErr.Code == code {
+				return true
+			}
+		}
+		return false
+	}
+
+	return true
+}
+
+// backoff returns the wait before retry number attempt+1: an exponential
+// delay with jitter, so workers retrying together don't hit the server in
+// lockstep.
+func backoff(attempt int) time.Duration {
+	d := maxBackoff
+	if attempt < 16 {
+		if exp := baseBackoff << uint(attempt); exp < maxBackoff {
+			d = exp
+		}
+	}
+
+	return d/2 + time.Duration(rand.Int63n(int64(d/2)))
+}
+
+// retryAfter parses a Retry-After header, given either in seconds or as an
+// HTTP date.
+func retryAfter(value string) time.Duration {
+	if value == "" {
+		return 0
+	}
+
+	var d time.Duration
+	if seconds, err := strconv.Atoi(value); err == nil {
+		d = time.Duration(seconds) * time.Second
+	} else if t, err := http.ParseTime(value); err == nil {
+		d = time.Until(t)
+	}
+
+	if d < 0 {
+		return 0
+	}
+	if d > maxRetryAfter {
+		return maxRetryAfter
+	}
+
+	return d
+}
+
+// sleep waits for d or until ctx is done.
+func sleep(ctx context.Context, d time.Duration) error {
+	timer := time.NewTimer(d)
+	defer timer.Stop()
+
+	select {
+	case <-timer.C:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
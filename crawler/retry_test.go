@@ -0,0 +1,119 @@
+package crawler
+
+import (
+	"context"
+	"fmt"
+	"testing"
+	"time"
+)
+
+func Test_retryable(t *testing.T) {
+	tests := []struct {
+		name     string
+		err      error
+		statuses []int
+		want     bool
+	}{
+		{
+			name: "Test connection error is retried",
+			err:  fmt.Errorf("dial tcp: connection refused"),
+			want: true,
+		},
+		{
+			name: "Test timeout is retried",
+			err:  fmt.Errorf("%w after 1s: http://example.com", ErrTimeout),
+			want: true,
+		},
+		{
+			name: "Test server error is retried",
+			err:  &StatusError{Code: 503},
+			want: true,
+		},
+		{
+			name: "Test too many requests is retried",
+			err:  &StatusError{Code: 429},
+			want: true,
+		},
+		{
+			name: "Test not found fails immediately",
+			err:  &StatusError{Code: 404},
+			want: false,
+		},
+		{
+			name:     "Test configured status is retried",
+			err:      &StatusError{Code: 403},
+			statuses: []int{403, 503},
+			want:     true,
+		},
+		{
+			name:     "Test status left out of the configured ones fails immediately",
+			err:      &StatusError{Code: 500},
+			statuses: []int{403, 503},
+			want:     false,
+		},
+		{
+			name:     "Test connection error is retried with configured statuses",
+			err:      fmt.Errorf("dial tcp: connection refused"),
+			statuses: []int{403},
+			want:     true,
+		},
+		{
+			name: "Test cancellation fails immediately",
+			err:  fmt.Errorf("get: %w", context.Canceled),
+			want: false,
+		},
+		{
+			name: "Test oversized body fails immediately",
+			err:  fmt.Errorf("%w: too big", ErrTooLarge),
+			want: false,
+		},
+		{
+			name: "Test host down fails immediately",
+			err:  fmt.Errorf("%w: example.com", ErrHostDown),
+			want: false,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := retryable(tt.err, tt.statuses); got != tt.want {
+				t.Errorf("retryable(%v) = %v, want %v", tt.err, got, tt.want)
+			}
+		})
+	}
+}
+
+func Test_retryAfter(t *testing.T) {
+	tests := []struct {
+		name  string
+		value string
+		want  time.Duration
+	}{
+		{
+			name:  "Test missing header",
+			value: "",
+			want:  0,
+		},
+		{
+			name:  "Test seconds",
+			value: "3",
+			want:  3 * time.Second,
+		},
+		{
+			name:  "Test date in the past",
+			value: "Wed, 21 Oct 2015 07:28:00 GMT",
+			want:  0,
+		},
+		{
+			name:  "Test capped",
+			value: "86400",
+			want:  maxRetryAfter,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := retryAfter(tt.value); got != tt.want {
+				t.Errorf("retryAfter(%v) = %v, want %v", tt.value, got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,8 @@
+//go:build !unix
+
+package crawler
+
+// FileLimit reports false: the limit of open files is only read on Unix.
+func FileLimit() (int, bool) {
+	return 0, false
+}
@@ -0,0 +1,20 @@
+//go:build unix
+
+package crawler
+
+import "syscall"
+
+// FileLimit returns the soft limit of open files of the process, reporting
+// false when there is none or it can't be read.
+func FileLimit() (int, bool) {
+	var limit syscall.Rlimit
+	if err := syscall.Getrlimit(syscall.RLIMIT_NOFILE, &limit); err != nil {
+		return 0, false
+	}
+	// unlimited reads as a huge value
+	if limit.Cur == 0 || limit.Cur > 1<<30 {
+		return 0, false
+	}
+
+	return int(limit.Cur), true
+}
@@ -0,0 +1,261 @@
+package crawler
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"regexp"
+	"strconv"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// maxRobotsSize caps how much of a robots.txt file is read.
+const maxRobotsSize = 500 * 1024
+
+type robotsRule struct {
+	allow   bool
+	pattern string
+	re      *regexp.Regexp
+}
+
+// robotsRules holds the rules of the robots.txt group that applies to us.
+type robotsRules struct {
+	rules []robotsRule
+	delay time.Duration
+}
+
+// disallowAll are the rules of a host whose robots.txt can't be trusted to
+// allow anything.
+var disallowAll = &robotsRules{rules: []robotsRule{{pattern: "/", re: robotsPattern("/")}}}
+
+// robotsEntry caches the rules of a host's robots.txt. The mutex makes sure
+// it is fetched only once, even when many workers hit the host at the same
+// time, and once again when it expires.
+type robotsEntry struct {
+	mutex     sync.Mutex
+	fetchedAt time.Time
+	rules     atomic.Pointer[robotsRules]
+}
+
+// allowed reports whether the crawler may fetch u according to the robots.txt
+// of its host. Failing to fetch robots.txt allows everything.
+func (c *Crawler) allowed(ctx context.Context, u *url.URL) bool {
+	if c.IgnoreRobots {
+		return true
+	}
+
+	return c.robotsRules(ctx, u).allowed(u.EscapedPath())
+}
+
+// robotsRules returns the rules of u's host, fetching its robots.txt the
+// first time and whenever the cached copy is older than RobotsTTL.
+func (c *Crawler) robotsRules(ctx context.Context, u *url.URL) *robotsRules {
+	v, _ := c.robots.LoadOrStore(u.Host, &robotsEntry{})
+	entry := v.(*robotsEntry)
+
+	entry.mutex.Lock()
+	defer entry.mutex.Unlock()
+
+	rules := entry.rules.Load()
+	if rules == nil || (c.RobotsTTL > 0 && time.Since(entry.fetchedAt) > c.RobotsTTL) {
+		rules = c.fetchRobots(ctx, u)
+		entry.rules.Store(rules)
+		entry.fetchedAt = time.Now()
+	}
+
+	return rules
+}
+
+// prefetchRobots fetches the robots.txt of the host of target in the
+// background the first time a url on it is queued, so it is usually cached
+// by the time a worker needs it. Sequential crawls don't, to keep the order
+// of their requests.
+func (c *Crawler) prefetchRobots(target string) {
+	if c.IgnoreRobots || c.Sequential || c.robotsCtx == nil {
+		return
+	}
+
+	u, err := url.Parse(target)
+	if err != nil || u.Host == "" {
+		return
+	}
+	if _, seen := c.robots.Load(u.Host); seen {
+		return
+	}
+
+	c.prefetches.Add(1)
+	go func() {
+		defer c.prefetches.Done()
+		c.robotsRules(c.robotsCtx, u)
+	}()
+}
+
+func (c *Crawler) fetchRobots(ctx context.Context, u *url.URL) *robotsRules {
+	robotsURL := fmt.Sprintf("%v://%v/robots.txt", u.Scheme, u.Host)
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, robotsURL, nil)
+	if err != nil {
+		return &robotsRules{}
+	}
+	c.setHeaders(req)
+
+	resp, err := c.client.Do(req)
+	if err != nil {
+		c.Logger.Warn("error fetching robots.txt", "url", robotsURL, "err", err)
+		return &robotsRules{}
+	}
+	defer resp.Body.Close()
+
+	// a missing robots.txt allows everything. one the server failed to
+	// serve may hide rules, so StrictRobots plays safe
+	if resp.StatusCode >= 500 && c.StrictRobots {
+		c.Logger.Warn("robots.txt unavailable, disallowing the host", "url", robotsURL, "status", resp.StatusCode)
+		return disallowAll
+	}
+	if resp.StatusCode != http.StatusOK {
+		return &robotsRules{}
+	}
+
+	data, err := io.ReadAll(io.LimitReader(resp.Body, maxRobotsSize))
+	if err != nil {
+		c.Logger.Warn("error reading robots.txt", "url", robotsURL, "err", err)
+		return &robotsRules{}
+	}
+
+	return parseRobots(data, c.UserAgent)
+}
+
+// parseRobots extracts the rules that apply to agent: the groups naming the
+// longest matching User-agent, or the "*" groups when none match.
+func parseRobots(data []byte, agent string) *robotsRules {
+	type group struct {
+		agents []string
+		rules  []robotsRule
+		delay  time.Duration
+		closed bool
+	}
+
+	agent = strings.ToLower(agent)
+	groups := []*group{}
+	var current *group
+
+	scanner := bufio.NewScanner(bytes.NewReader(data))
+	for scanner.Scan() {
+		line := scanner.Text()
+		if i := strings.Index(line, "#"); i >= 0 {
+			line = line[:i]
+		}
+
+		key, value, ok := strings.Cut(line, ":")
+		if !ok {
+			continue
+		}
+		key = strings.ToLower(strings.TrimSpace(key))
+		value = strings.TrimSpace(value)
+
+		switch key {
+		case "user-agent":
+			// consecutive User-agent lines share the same group
+			if current == nil || current.closed {
+				current = &group{}
+				groups = append(groups, current)
+			}
+			current.agents = append(current.agents, strings.ToLower(value))
+		case "crawl-delay":
+			if current == nil {
+				continue
+			}
+			current.closed = true
+			if seconds, err := strconv.ParseFloat(value, 64); err == nil && seconds > 0 {
+				current.delay = time.Duration(seconds * float64(time.Second))
+			}
+		case "allow", "disallow":
+			if current == nil {
+				continue
+			}
+			current.closed = true
+			if value == "" {
+				continue
+			}
+			current.rules = append(current.rules, robotsRule{
+				allow:   key == "allow",
+				pattern: value,
+				re:      robotsPattern(value),
+			})
+		}
+	}
+
+	best := -1
+	rules := &robotsRules{}
+	for _, g := range groups {
+		n := -1
+		for _, a := range g.agents {
+			if a == "*" && n < 0 {
+				n = 0
+			} else if a != "*" && strings.Contains(agent, a) && len(a) > n {
+				n = len(a)
+			}
+		}
+
+		if n < 0 {
+			continue
+		}
+		if n > best {
+			best = n
+			rules.rules = nil
+			rules.delay = 0
+		}
+		if n == best {
+			rules.rules = append(rules.rules, g.rules...)
+			if g.delay > rules.delay {
+				rules.delay = g.delay
+			}
+		}
+	}
+
+	return rules
+}
+
+// robotsPattern turns a robots.txt path pattern, which may use "*" and a
+// trailing "$", into a regexp anchored at the start of the path.
+func robotsPattern(pattern string) *regexp.Regexp {
+	end := strings.HasSuffix(pattern, "$")
+	pattern = strings.TrimSuffix(pattern, "$")
+
+	expr := "^" + strings.ReplaceAll(regexp.QuoteMeta(pattern), `\*`, ".*")
+	if end {
+		expr += "$"
+	}
+
+	return regexp.MustCompile(expr)
+}
+
+// allowed applies the most specific (longest) matching rule to p. On a tie
+// Allow wins, and a path matching no rule is allowed.
+func (r *robotsRules) allowed(p string) bool {
+	if p == "" {
+		p = "/"
+	}
+
+	allow := true
+	longest := -1
+	for _, rule := range r.rules {
+		if !rule.re.MatchString(p) {
+			continue
+		}
+
+		if len(rule.pattern) > longest || (len(rule.pattern) == longest && rule.allow) {
+			longest = len(rule.pattern)
+			allow = rule.allow
+		}
+	}
+
+	return allow
+}
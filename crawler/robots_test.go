@@ -0,0 +1,265 @@
+package crawler
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func Test_parseRobots(t *testing.T) {
+	robots := `
+# comment
+User-agent: *
+Disallow: /private
+Allow: /private/open
+
+User-agent: other-bot
+Disallow: /
+
+User-agent: web-crawler
+User-agent: friend
+Disallow: /secret
+Disallow: /*.pdf$
+Allow: /secret/public
+`
+
+	type args struct {
+		agent string
+		path  string
+	}
+	tests := []struct {
+		name string
+		args args
+		want bool
+	}{
+		{
+			name: "Test wildcard group disallows",
+			args: args{agent: "unknown", path: "/private/page"},
+			want: false,
+		},
+		{
+			name: "Test longer allow wins",
+			args: args{agent: "unknown", path: "/private/open/page"},
+			want: true,
+		},
+		{
+			name: "Test specific group replaces wildcard group",
+			args: args{agent: "web-crawler/1.0", path: "/private/page"},
+			want: true,
+		},
+		{
+			name: "Test specific group disallows",
+			args: args{agent: "web-crawler/1.0", path: "/secret/page"},
+			want: false,
+		},
+		{
+			name: "Test specific group allow",
+			args: args{agent: "web-crawler/1.0", path: "/secret/public/page"},
+			want: true,
+		},
+		{
+			name: "Test wildcard and end anchor",
+			args: args{agent: "web-crawler/1.0", path: "/docs/file.pdf"},
+			want: false,
+		},
+		{
+			name: "Test end anchor does not match longer paths",
+			args: args{agent: "web-crawler/1.0", path: "/docs/file.pdf.html"},
+			want: true,
+		},
+		{
+			name: "Test disallow all",
+			args: args{agent: "other-bot", path: "/anything"},
+			want: false,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			rules := parseRobots([]byte(robots), tt.args.agent)
+			if got := rules.allowed(tt.args.path); got != tt.want {
+				t.Errorf("allowed(%v) = %v, want %v", tt.args.path, got, tt.want)
+			}
+		})
+	}
+}
+
+func Test_parseRobotsCrawlDelay(t *testing.T) {
+	robots := `
+User-agent: *
+Crawl-delay: 2
+
+User-agent: web-crawler
+Crawl-delay: 0.5
+Disallow:
+`
+
+	tests := []struct {
+		name  string
+		agent string
+		want  time.Duration
+	}{
+		{
+			name:  "Test wildcard crawl delay",
+			agent: "unknown",
+			want:  2 * time.Second,
+		},
+		{
+			name:  "Test fractional crawl delay of specific group",
+			agent: "web-crawler/1.0",
+			want:  500 * time.Millisecond,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := parseRobots([]byte(robots), tt.agent).delay; got != tt.want {
+				t.Errorf("delay = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestCrawler_allowedCache(t *testing.T) {
+	var fetches atomic.Int64
+	status := http.StatusOK
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/robots.txt" {
+			http.NotFound(w, r)
+			return
+		}
+		fetches.Add(1)
+		if status != http.StatusOK {
+			w.WriteHeader(status)
+			return
+		}
+		fmt.Fprint(w, "User-agent: *\nDisallow: /private\n")
+	}))
+	defer server.Close()
+
+	tests := []struct {
+		name        string
+		status      int
+		strict      bool
+		ttl         time.Duration
+		wait        time.Duration
+		wantAllowed bool
+		wantFetches int64
+	}{
+		{
+			name:        "Test concurrent workers fetch robots.txt once",
+			status:      http.StatusOK,
+			wantAllowed: false,
+			wantFetches: 1,
+		},
+		{
+			name:        "Test cached rules are kept within the ttl",
+			status:      http.StatusOK,
+			ttl:         time.Hour,
+			wait:        10 * time.Millisecond,
+			wantAllowed: false,
+			wantFetches: 1,
+		},
+		{
+			name:        "Test expired rules are fetched again",
+			status:      http.StatusOK,
+			ttl:         time.Millisecond,
+			wait:        10 * time.Millisecond,
+			wantAllowed: false,
+			wantFetches: 2,
+		},
+		{
+			name:        "Test missing robots.txt allows everything",
+			status:      http.StatusNotFound,
+			strict:      true,
+			wantAllowed: true,
+			wantFetches: 1,
+		},
+		{
+			name:        "Test unavailable robots.txt allows everything",
+			status:      http.StatusServiceUnavailable,
+			wantAllowed: true,
+			wantFetches: 1,
+		},
+		{
+			name:        "Test unavailable robots.txt disallows when strict",
+			status:      http.StatusServiceUnavailable,
+			strict:      true,
+			wantAllowed: false,
+			wantFetches: 1,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			fetches.Store(0)
+			status = tt.status
+
+			c := New(server.URL, t.TempDir())
+			c.RobotsTTL = tt.ttl
+			c.StrictRobots = tt.strict
+			client, err := c.newClient()
+			if err != nil {
+				t.Fatal(err)
+			}
+			c.client = client
+
+			u, _ := url.Parse(server.URL + "/private/page")
+			var wg sync.WaitGroup
+			for i := 0; i < 10; i++ {
+				wg.Add(1)
+				go func() {
+					defer wg.Done()
+					if got := c.allowed(context.Background(), u); got != tt.wantAllowed {
+						t.Errorf("allowed() = %v, want %v", got, tt.wantAllowed)
+					}
+				}()
+			}
+			wg.Wait()
+
+			if tt.wait > 0 {
+				time.Sleep(tt.wait)
+				c.allowed(context.Background(), u)
+			}
+
+			if got := fetches.Load(); got != tt.wantFetches {
+				t.Errorf("robots.txt fetched %v times, want %v", got, tt.wantFetches)
+			}
+		})
+	}
+}
+
+func TestCrawler_prefetchRobots(t *testing.T) {
+	var fetches atomic.Int64
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/robots.txt" {
+			fetches.Add(1)
+		}
+		fmt.Fprint(w, "User-agent: *\nDisallow: /private\n")
+	}))
+	defer server.Close()
+
+	c := New(server.URL, t.TempDir())
+	client, err := c.newClient()
+	if err != nil {
+		t.Fatal(err)
+	}
+	c.client = client
+	c.robotsCtx = context.Background()
+
+	for i := 0; i < 3; i++ {
+		c.prefetchRobots(server.URL + "/docs")
+	}
+	c.prefetches.Wait()
+
+	if got := fetches.Load(); got != 1 {
+		t.Errorf("robots.txt fetched %v times, want 1", got)
+	}
+	u, _ := url.Parse(server.URL)
+	if v, ok := c.robots.Load(u.Host); !ok || v.(*robotsEntry).rules.Load() == nil {
+		t.Errorf("robots.txt of %v wasn't cached", server.URL)
+	}
+}
@@ -0,0 +1,178 @@
+package crawler
+
+import (
+	"bytes"
+	"crypto/hmac"
+	"crypto/sha256"
+	"fmt"
+	"io"
+	"net/http"
+	"path"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+)
+
+// S3 is a Store saving to a bucket of Amazon S3, or of any service speaking
+// its API, with requests signed with AWS Signature Version 4. Objects are
+// named by Prefix joined with the key, so the output directory is part of
+// their name.
+type S3 struct {
+	// Endpoint is the base url of the service, s3.<Region>.amazonaws.com
+	// over https when empty. Buckets are addressed in the path.
+	Endpoint string
+
+	Region string
+	Bucket string
+	Prefix string
+
+	AccessKey    string
+	SecretKey    string
+	SessionToken string
+
+	// Client sends the requests, http.DefaultClient when nil.
+	Client *http.Client
+}
+
+// emptyHash is the SHA-256 of an empty payload.
+const emptyHash = "e3b0c44298fc1c149afbf4c8996fb92427ae41e4649b934ca495991b7852b855"
+
+func (s *S3) Get(key string) ([]byte, bool) {
+	req, err := s.request(http.MethodGet, key, nil)
+	if err != nil {
+		return nil, false
+	}
+	s.sign(req, emptyHash, time.Now())
+
+	resp, err := s.client().Do(req)
+	if err != nil {
+		return nil, false
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return nil, false
+	}
+
+	data, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return nil, false
+	}
+
+	return data, true
+}
+
+func (s *S3) Put(key string, data []byte, meta ObjectMeta) error {
+	req, err := s.request(http.MethodPut, key, bytes.NewReader(data))
+	if err != nil {
+		return err
+	}
+	if meta.ContentType != "" {
+		req.Header.Set("Content-Type", meta.ContentType)
+	}
+	if meta.URL != "" {
+		req.Header.Set("X-Amz-Meta-Url", meta.URL)
+	}
+	s.sign(req, contentHash(data), time.Now())
+
+	resp, err := s.client().Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode/100 != 2 {
+		body, _ := io.ReadAll(io.LimitReader(resp.Body, 512))
+		return fmt.Errorf("s3: putting %v: %v: %s", req.URL.Path, resp.Status, bytes.TrimSpace(body))
+	}
+
+	return nil
+}
+
+// request returns an unsigned request for the object saved as key.
+func (s *S3) request(method, key string, body io.Reader) (*http.Request, error) {
+	endpoint := s.Endpoint
+	if endpoint == "" {
+		endpoint = "https://s3." + s.Region + ".amazonaws.com"
+	}
+	object := strings.TrimPrefix(path.Join(s.Prefix, filepath.ToSlash(key)), "/")
+
+	return http.NewRequest(method, strings.TrimSuffix(endpoint, "/")+"/"+s.Bucket+"/"+awsEscape(object), body)
+}
+
+// sign adds the Authorization header of AWS Signature Version 4 to req,
+// covering its host and every header it has.
+func (s *S3) sign(req *http.Request, payloadHash string, now time.Time) {
+	now = now.UTC()
+	amzDate := now.Format("20060102T150405Z")
+	date := now.Format("20060102")
+
+	req.Header.Set("X-Amz-Date", amzDate)
+	req.Header.Set("X-Amz-Content-Sha256", payloadHash)
+	if s.SessionToken != "" {
+		req.Header.Set("X-Amz-Security-Token", s.SessionToken)
+	}
+
+	headers := map[string]string{"host": req.URL.Host}
+	for k, values := range req.Header {
+		headers[strings.ToLower(k)] = strings.TrimSpace(strings.Join(values, ","))
+	}
+	names := make([]string, 0, len(headers))
+	for name := range headers {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	var canonical strings.Builder
+	fmt.Fprintf(&canonical, "%v\n%v\n%v\n", req.Method, req.URL.EscapedPath(), req.URL.RawQuery)
+	for _, name := range names {
+		fmt.Fprintf(&canonical, "%v:%v\n", name, headers[name])
+	}
+	signed := strings.Join(names, ";")
+	fmt.Fprintf(&canonical, "\n%v\n%v", signed, payloadHash)
+
+	scope := date + "/" + s.Region + "/s3/aws4_request"
+	toSign := "AWS4-HMAC-SHA256\n" + amzDate + "\n" + scope + "\n" + contentHash([]byte(canonical.String()))
+
+	key := []byte("AWS4" + s.SecretKey)
+	for _, part := range []string{date, s.Region, "s3", "aws4_request"} {
+		key = hmacSHA256(key, part)
+	}
+
+	req.Header.Set("Authorization", fmt.Sprintf("AWS4-HMAC-SHA256 Credential=%v/%v, SignedHeaders=%v, Signature=%x",
+		s.AccessKey, scope, signed, hmacSHA256(key, toSign)))
+}
+
+func (s *S3) client() *http.Client {
+	if s.Client == nil {
+		return http.DefaultClient
+	}
+
+	return s.Client
+}
+
+func hmacSHA256(key []byte, data string) []byte {
+	h := hmac.New(sha256.New, key)
+	h.Write([]byte(data))
+
+	return h.Sum(nil)
+}
+
+// awsEscape percent-encodes an object name the way Signature Version 4
+// expects, everything but unreserved characters and slashes.
+func awsEscape(s string) string {
+	var b strings.Builder
+	for i := 0; i < len(s); i++ {
+		c := s[i]
+		switch {
+		case 'A' <= c && c <= 'Z', 'a' <= c && c <= 'z', '0' <= c && c <= '9',
+			c == '-', c == '_', c == '.', c == '~', c == '/':
+			b.WriteByte(c)
+		default:
+			fmt.Fprintf(&b, "%%%02X", c)
+		}
+	}
+
+	return b.String()
+}
@@ -0,0 +1,77 @@
+package crawler
+
+import (
+	"net/url"
+	"strings"
+
+	"golang.org/x/net/publicsuffix"
+)
+
+// The crawl scopes a Crawler supports.
+const (
+	ScopePath   = "path"
+	ScopeHost   = "host"
+	ScopeDomain = "domain"
+)
+
+// registeredDomain returns the domain a host was registered under (eTLD+1),
+// e.g. example.co.uk for www.example.co.uk. Hosts without one, like IPs or
+// localhost, are returned as is.
+func registeredDomain(host string) string {
+	domain, err := publicsuffix.EffectiveTLDPlusOne(host)
+	if err != nil {
+		return host
+	}
+
+	return domain
+}
+
+// inDomain reports whether host is domain or one of its subdomains. It
+// matches whole labels, so notexample.com isn't part of example.com.
+func inDomain(host, domain string) bool {
+	host = strings.ToLower(host)
+	domain = strings.ToLower(domain)
+
+	return host == domain || strings.HasSuffix(host, "."+domain)
+}
+
+// sameSite reports whether u is on the same host as base, or on a subdomain
+// of the same registered domain with IncludeSubdomains or ScopeDomain. With
+// AllowedDomains, it reports whether u is within one of them instead.
+func (c *Crawler) sameSite(u, base *url.URL) bool {
+	if len(c.AllowedDomains) > 0 {
+		for _, domain := range c.AllowedDomains {
+			if inDomain(u.Hostname(), domain) {
+				return true
+			}
+		}
+		return false
+	}
+
+	if u.Host == base.Host {
+		return true
+	}
+
+	return (c.IncludeSubdomains || c.Scope == ScopeDomain) && inDomain(u.Hostname(), registeredDomain(base.Hostname()))
+}
+
+// inScope reports whether the link newURL, given as host and path, may be
+// followed from the page at pageURL. ScopePath keeps the children of the
+// page; the other scopes take any path on a host sameSite accepted.
+func (c *Crawler) inScope(newURL, pageURL string, subdomain bool) bool {
+	if subdomain || c.Scope == ScopeHost || c.Scope == ScopeDomain {
+		return true
+	}
+
+	return checkIfChildren(c.foldPath(newURL), c.foldPath(pageURL))
+}
+
+// foldPath returns a host and path lowercased under CaseInsensitivePaths,
+// to compare them, or as they are otherwise.
+func (c *Crawler) foldPath(hostPath string) string {
+	if c.CaseInsensitivePaths {
+		return strings.ToLower(hostPath)
+	}
+
+	return hostPath
+}
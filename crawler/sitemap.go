@@ -0,0 +1,205 @@
+package crawler
+
+import (
+	"bytes"
+	"compress/gzip"
+	"context"
+	"encoding/xml"
+	"fmt"
+	"io"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// maxSitemapURLs is the most urls a single sitemap may list.
+const maxSitemapURLs = 50000
+
+const sitemapNamespace = "http://www.sitemaps.org/schemas/sitemap/0.9"
+
+type sitemapURL struct {
+	Loc     string `xml:"loc"`
+	LastMod string `xml:"lastmod,omitempty"`
+}
+
+type sitemapURLSet struct {
+	XMLName xml.Name     `xml:"urlset"`
+	Xmlns   string       `xml:"xmlns,attr"`
+	URLs    []sitemapURL `xml:"url"`
+}
+
+type sitemapIndex struct {
+	XMLName  xml.Name     `xml:"sitemapindex"`
+	Xmlns    string       `xml:"xmlns,attr"`
+	Sitemaps []sitemapURL `xml:"sitemap"`
+}
+
+// WriteSitemap writes a sitemap of every page crawled successfully to
+// fileName. Past 50,000 urls the pages are split over fileName-1.xml,
+// fileName-2.xml... and fileName becomes a sitemap index pointing at them,
+// as if they were published next to the target's root.
+func (c *Crawler) WriteSitemap(fileName string) error {
+	urls := c.sitemapURLs()
+	if len(urls) <= maxSitemapURLs {
+		return writeXML(fileName, sitemapURLSet{Xmlns: sitemapNamespace, URLs: urls})
+	}
+
+	root, err := url.Parse(c.target)
+	if err != nil {
+		return err
+	}
+
+	ext := filepath.Ext(fileName)
+	prefix := strings.TrimSuffix(fileName, ext)
+	index := sitemapIndex{Xmlns: sitemapNamespace}
+	for i := 0; i*maxSitemapURLs < len(urls); i++ {
+		end := (i + 1) * maxSitemapURLs
+		if end > len(urls) {
+			end = len(urls)
+		}
+
+		part := fmt.Sprintf("%v-%v%v", prefix, i+1, ext)
+		if err := writeXML(part, sitemapURLSet{Xmlns: sitemapNamespace, URLs: urls[i*maxSitemapURLs : end]}); err != nil {
+			return err
+		}
+
+		loc := root.ResolveReference(&url.URL{Path: "/" + filepath.Base(part)})
+		index.Sitemaps = append(index.Sitemaps, sitemapURL{Loc: loc.String()})
+	}
+
+	return writeXML(fileName, index)
+}
+
+// sitemapURLs lists the pages worth indexing: those that were saved or
+// found in the cache and don't ask not to be indexed, under the url they
+// were finally served from.
+func (c *Crawler) sitemapURLs() []sitemapURL {
+	urls := []sitemapURL{}
+	seen := map[string]bool{}
+
+	for _, rec := range c.Records() {
+		if rec.Asset || rec.NoIndex || rec.Error != "" || rec.Path == "" {
+			continue
+		}
+
+		loc := rec.URL
+		if rec.FinalURL != "" {
+			loc = rec.FinalURL
+		}
+		if rec.Canonical != "" {
+			loc = rec.Canonical
+		}
+		if seen[loc] {
+			continue
+		}
+		seen[loc] = true
+
+		urls = append(urls, sitemapURL{Loc: loc, LastMod: rec.LastModified})
+	}
+
+	return urls
+}
+
+func writeXML(fileName string, v interface{}) error {
+	data, err := xml.MarshalIndent(v, "", "  ")
+	if err != nil {
+		return err
+	}
+
+	return os.WriteFile(fileName, append([]byte(xml.Header), data...), 0644)
+}
+
+// maxSitemapDepth is how many levels of sitemap indexes are followed when
+// seeding from a sitemap.
+const maxSitemapDepth = 3
+
+// sitemapSeeds fetches the target's /sitemap.xml, following sitemap
+// indexes, and returns the pages it lists that are in scope. A sitemap that
+// can't be fetched or parsed is logged and skipped.
+func (c *Crawler) sitemapSeeds(ctx context.Context) []string {
+	target, err := url.Parse(c.target)
+	if err != nil {
+		return nil
+	}
+	page := normalizeURL(target, c.LowercasePaths)
+
+	seeds := []string{}
+	seen := map[string]bool{}
+
+	var read func(loc string, depth int)
+	read = func(loc string, depth int) {
+		if seen[loc] || depth > maxSitemapDepth {
+			return
+		}
+		seen[loc] = true
+
+		resp, err := c.download(ctx, loc)
+		if err != nil {
+			c.Logger.Warn("error fetching the sitemap", "url", loc, "err", err)
+			return
+		}
+
+		pages, sitemaps, err := parseSitemap(resp.body)
+		if err != nil {
+			c.Logger.Warn("error parsing the sitemap", "url", loc, "err", err)
+			return
+		}
+		c.Logger.Debug("read sitemap", "url", loc, "pages", len(pages), "sitemaps", len(sitemaps))
+
+		for _, p := range pages {
+			u, err := url.Parse(p)
+			if err != nil || (u.Scheme != "http" && u.Scheme != "https") {
+				continue
+			}
+			n := normalizeURL(u, c.LowercasePaths)
+			if c.sameSite(n, page) && c.inScope(n.Host+n.Path, page.Host+page.Path, n.Host != page.Host) && c.filtered(p) {
+				seeds = append(seeds, p)
+			}
+		}
+
+		for _, s := range sitemaps {
+			if u, err := url.Parse(s); err == nil && c.sameSite(normalizeURL(u, false), page) {
+				read(s, depth+1)
+			}
+		}
+	}
+	read(fmt.Sprintf("%v://%v/sitemap.xml", target.Scheme, target.Host), 0)
+
+	return seeds
+}
+
+// parseSitemap returns the page urls of a sitemap, or the sitemap urls of a
+// sitemap index. Gzipped sitemaps are decompressed first.
+func parseSitemap(data []byte) (pages, sitemaps []string, err error) {
+	if bytes.HasPrefix(data, []byte{0x1f, 0x8b}) {
+		gz, err := gzip.NewReader(bytes.NewReader(data))
+		if err != nil {
+			return nil, nil, err
+		}
+		if data, err = io.ReadAll(gz); err != nil {
+			return nil, nil, err
+		}
+	}
+
+	var doc struct {
+		URLs     []sitemapURL `xml:"url"`
+		Sitemaps []sitemapURL `xml:"sitemap"`
+	}
+	if err := xml.Unmarshal(data, &doc); err != nil {
+		return nil, nil, err
+	}
+
+	for _, u := range doc.URLs {
+		if loc := strings.TrimSpace(u.Loc); loc != "" {
+			pages = append(pages, loc)
+		}
+	}
+	for _, s := range doc.Sitemaps {
+		if loc := strings.TrimSpace(s.Loc); loc != "" {
+			sitemaps = append(sitemaps, loc)
+		}
+	}
+
+	return pages, sitemaps, nil
+}
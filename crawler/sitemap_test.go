@@ -0,0 +1,210 @@
+package crawler
+
+import (
+	"bytes"
+	"compress/gzip"
+	"context"
+	"encoding/xml"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"reflect"
+	"sort"
+	"strings"
+	"testing"
+)
+
+func TestCrawler_WriteSitemap(t *testing.T) {
+	many := []Record{}
+	for i := 0; i <= maxSitemapURLs; i++ {
+		many = append(many, Record{URL: fmt.Sprintf("https://example.com/p%06d", i), Path: "p"})
+	}
+
+	tests := []struct {
+		name      string
+		records   []Record
+		wantURLs  []sitemapURL
+		wantParts int
+	}{
+		{
+			name: "Test only saved pages are listed",
+			records: []Record{
+				{URL: "https://example.com", Path: "index.html", LastModified: "2023-03-01T10:00:00Z"},
+				{URL: "https://example.com/old", FinalURL: "https://example.com/new", Path: "new.html"},
+				{URL: "https://example.com/cached", Path: "cached.html", Cached: true},
+				{URL: "https://example.com/missing", Error: "invalid status code 404"},
+				{URL: "https://example.com/logo.png", Path: "logo.png", Asset: true},
+				{URL: "https://example.com/skipped"},
+			},
+			wantURLs: []sitemapURL{
+				{Loc: "https://example.com", LastMod: "2023-03-01T10:00:00Z"},
+				{Loc: "https://example.com/cached"},
+				{Loc: "https://example.com/new"},
+			},
+		},
+		{
+			name:      "Test large sitemaps are split",
+			records:   many,
+			wantParts: 2,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			c := New("https://example.com", t.TempDir())
+			for _, rec := range tt.records {
+				c.addRecord(rec)
+			}
+
+			fileName := filepath.Join(t.TempDir(), "sitemap.xml")
+			if err := c.WriteSitemap(fileName); err != nil {
+				t.Fatalf("WriteSitemap() error = %v", err)
+			}
+
+			data, err := os.ReadFile(fileName)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			if tt.wantParts == 0 {
+				var set sitemapURLSet
+				if err := xml.Unmarshal(data, &set); err != nil {
+					t.Fatal(err)
+				}
+				if !reflect.DeepEqual(set.URLs, tt.wantURLs) {
+					t.Errorf("WriteSitemap() urls = %v, want %v", set.URLs, tt.wantURLs)
+				}
+				return
+			}
+
+			var index sitemapIndex
+			if err := xml.Unmarshal(data, &index); err != nil {
+				t.Fatal(err)
+			}
+			if len(index.Sitemaps) != tt.wantParts {
+				t.Fatalf("WriteSitemap() index = %v, want %v parts", index.Sitemaps, tt.wantParts)
+			}
+			if want := "https://example.com/sitemap-2.xml"; index.Sitemaps[1].Loc != want {
+				t.Errorf("WriteSitemap() part = %v, want %v", index.Sitemaps[1].Loc, want)
+			}
+
+			total := 0
+			for i := 1; i <= tt.wantParts; i++ {
+				data, err := os.ReadFile(filepath.Join(filepath.Dir(fileName), fmt.Sprintf("sitemap-%v.xml", i)))
+				if err != nil {
+					t.Fatal(err)
+				}
+				var set sitemapURLSet
+				if err := xml.Unmarshal(data, &set); err != nil {
+					t.Fatal(err)
+				}
+				total += len(set.URLs)
+			}
+			if total != len(tt.records) {
+				t.Errorf("WriteSitemap() listed %v urls, want %v", total, len(tt.records))
+			}
+		})
+	}
+}
+
+func TestCrawler_RunFromSitemap(t *testing.T) {
+	var server *httptest.Server
+	server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/docs":
+			fmt.Fprint(w, `<p>no links</p>`)
+		case "/docs/orphan", "/blog/post":
+			fmt.Fprint(w, `<p>page</p>`)
+		case "/sitemap.xml":
+			fmt.Fprintf(w, `<sitemapindex><sitemap><loc>%v/sitemap-docs.xml</loc></sitemap></sitemapindex>`, server.URL)
+		case "/sitemap-docs.xml":
+			fmt.Fprintf(w, `<urlset><url><loc>%[1]v/docs/orphan</loc></url><url><loc>%[1]v/blog/post</loc></url><url><loc>https://other.com/docs/x</loc></url></urlset>`, server.URL)
+		default:
+			http.NotFound(w, r)
+		}
+	}))
+	defer server.Close()
+
+	tests := []struct {
+		name        string
+		fromSitemap bool
+		want        []string
+	}{
+		{
+			name: "Test sitemap is ignored by default",
+			want: []string{"/docs"},
+		},
+		{
+			name:        "Test pages in the sitemap and in scope are crawled",
+			fromSitemap: true,
+			want:        []string{"/docs", "/docs/orphan"},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			c := New(server.URL+"/docs", t.TempDir())
+			c.IgnoreRobots = true
+			c.FromSitemap = tt.fromSitemap
+			if err := c.Run(context.Background()); err != nil {
+				t.Fatalf("Run() error = %v", err)
+			}
+
+			got := []string{}
+			for _, rec := range c.Records() {
+				got = append(got, strings.TrimPrefix(rec.URL, server.URL))
+			}
+			sort.Strings(got)
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("Run() crawled %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func Test_parseSitemap(t *testing.T) {
+	var gz bytes.Buffer
+	w := gzip.NewWriter(&gz)
+	fmt.Fprint(w, `<urlset><url><loc>https://example.com/a</loc></url></urlset>`)
+	w.Close()
+
+	tests := []struct {
+		name         string
+		data         []byte
+		wantPages    []string
+		wantSitemaps []string
+		wantErr      bool
+	}{
+		{
+			name:      "Test url set",
+			data:      []byte(`<urlset xmlns="http://www.sitemaps.org/schemas/sitemap/0.9"><url><loc> https://example.com/a </loc></url><url><loc>https://example.com/b</loc></url></urlset>`),
+			wantPages: []string{"https://example.com/a", "https://example.com/b"},
+		},
+		{
+			name:         "Test sitemap index",
+			data:         []byte(`<sitemapindex><sitemap><loc>https://example.com/s1.xml</loc></sitemap></sitemapindex>`),
+			wantSitemaps: []string{"https://example.com/s1.xml"},
+		},
+		{
+			name:      "Test gzipped sitemap",
+			data:      gz.Bytes(),
+			wantPages: []string{"https://example.com/a"},
+		},
+		{
+			name:    "Test not xml",
+			data:    []byte(`<html`),
+			wantErr: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			pages, sitemaps, err := parseSitemap(tt.data)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("parseSitemap() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if !reflect.DeepEqual(pages, tt.wantPages) || !reflect.DeepEqual(sitemaps, tt.wantSitemaps) {
+				t.Errorf("parseSitemap() = %v, %v, want %v, %v", pages, sitemaps, tt.wantPages, tt.wantSitemaps)
+			}
+		})
+	}
+}
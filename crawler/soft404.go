@@ -0,0 +1,104 @@
+package crawler
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"fmt"
+	"net/url"
+	"sync"
+)
+
+// soft404Tolerance is how much the length of a page may differ from the
+// one of a host's not found page, as a fraction of it, for a page with the
+// same title to match it.
+const soft404Tolerance = 0.1
+
+// pageFingerprint describes the page a host serves with a 200 for urls
+// that don't exist.
+type pageFingerprint struct {
+	hash   string
+	title  string
+	length int
+}
+
+// soft404Probe holds the fingerprint of a host, nil when it answers
+// unknown urls with a proper error or a redirect. The probe is sent once
+// per host.
+type soft404Probe struct {
+	once        sync.Once
+	fingerprint *pageFingerprint
+}
+
+// isSoft404 reports whether content, the body of a page of pageURL's host
+// answered with a 2xx, is the page the host serves for urls that don't
+// exist: the same body, or the same title and about the same length. The
+// target is never one.
+func (c *Crawler) isSoft404(ctx context.Context, pageURL *url.URL, content []byte) bool {
+	if target, err := url.Parse(c.target); err == nil && c.pageKey(target) == c.pageKey(pageURL) {
+		return false
+	}
+
+	fp := c.soft404Fingerprint(ctx, pageURL)
+	if fp == nil {
+		return false
+	}
+	if contentHash(content) == fp.hash {
+		return true
+	}
+
+	diff := len(content) - fp.length
+	if diff < 0 {
+		diff = -diff
+	}
+	if fp.title == "" || float64(diff) > soft404Tolerance*float64(fp.length) {
+		return false
+	}
+
+	doc, err := parseHTML(content)
+
+	return err == nil && pageTitle(doc) == fp.title
+}
+
+// soft404Fingerprint returns the fingerprint of the not found page of u's
+// host, probing a random path the first time the host is asked for. Other
+// workers wait for that probe.
+func (c *Crawler) soft404Fingerprint(ctx context.Context, u *url.URL) *pageFingerprint {
+	v, _ := c.soft404s.LoadOrStore(u.Host, &soft404Probe{})
+	probe := v.(*soft404Probe)
+	probe.once.Do(func() {
+		probe.fingerprint = c.probeSoft404(ctx, u)
+	})
+
+	return probe.fingerprint
+}
+
+func (c *Crawler) probeSoft404(ctx context.Context, u *url.URL) *pageFingerprint {
+	name := make([]byte, 12)
+	rand.Read(name)
+	probeURL := fmt.Sprintf("%v://%v/%v", u.Scheme, u.Host, hex.EncodeToString(name))
+
+	c.Logger.Debug("probing for soft 404s", "url", probeURL)
+	resp, err := c.download(ctx, probeURL)
+	if err != nil || resp.status/100 != 2 {
+		return nil
+	}
+	// a redirect, often to the home page, says nothing of what a missing
+	// page looks like
+	if resp.url != nil && resp.url.String() != probeURL {
+		c.Logger.Debug("unknown urls are redirected, not probing for soft 404s", "host", u.Host, "final_url", resp.url.String())
+		return nil
+	}
+
+	content := resp.body
+	if !c.NoTranscode {
+		content, _ = c.toUTF8(content, resp.contentType)
+	}
+	fp := &pageFingerprint{hash: contentHash(content), length: len(content)}
+	if doc, err := parseHTML(content); err == nil {
+		fp.title = pageTitle(doc)
+	}
+	c.Logger.Info("host answers unknown urls with a page, skipping the ones like it", "host", u.Host, "title", fp.title)
+
+	return fp
+}
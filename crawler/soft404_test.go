@@ -0,0 +1,87 @@
+package crawler
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestCrawler_RunDetectSoft404(t *testing.T) {
+	tests := []struct {
+		name     string
+		notFound func(w http.ResponseWriter, r *http.Request)
+		soft404s []string
+	}{
+		{
+			name: "not found page served with a 200",
+			notFound: func(w http.ResponseWriter, r *http.Request) {
+				fmt.Fprintf(w, `<title>Page not found</title><p>Sorry, there is nothing at %v.</p><p>%v</p><a href="/trap">home</a>`,
+					r.URL.Path, strings.Repeat("Try the search, or go back to the home page. ", 8))
+			},
+			soft404s: []string{"/gone", "/moved"},
+		},
+		{
+			name: "unknown paths redirected to the home page",
+			notFound: func(w http.ResponseWriter, r *http.Request) {
+				http.Redirect(w, r, "/", http.StatusFound)
+			},
+			soft404s: []string{},
+		},
+		{
+			name:     "proper 404",
+			notFound: http.NotFound,
+			soft404s: []string{},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			requested := map[string]bool{}
+			server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				requested[r.URL.Path] = true
+				switch r.URL.Path {
+				case "/", "/home":
+					fmt.Fprint(w, `<title>Home</title><a href="/home">home</a><a href="/a">a</a><a href="/gone">gone</a><a href="/moved">moved</a>`)
+				case "/a":
+					fmt.Fprint(w, `<title>Page not found</title><p>A page about the ways a page goes missing, a long one.</p>`)
+				default:
+					tt.notFound(w, r)
+				}
+			}))
+			defer server.Close()
+
+			c := New(server.URL, t.TempDir())
+			c.IgnoreRobots = true
+			c.Sequential = true
+			c.DetectSoft404 = true
+			c.Run(context.Background())
+
+			got := []string{}
+			for _, rec := range c.Records() {
+				path := strings.TrimPrefix(rec.URL, server.URL)
+				if rec.Soft404 {
+					got = append(got, path)
+					if rec.Path != "" {
+						t.Errorf("%v: Path = %q, want none", path, rec.Path)
+					}
+				}
+				if (path == "" || path == "/a" || path == "/home") && (rec.Soft404 || rec.Path == "") {
+					t.Errorf("%q wasn't saved: %+v", path, rec)
+				}
+			}
+			if !reflect.DeepEqual(got, tt.soft404s) {
+				t.Errorf("soft 404s = %v, want %v", got, tt.soft404s)
+			}
+			if st := c.Stats(); st.Soft404s != int64(len(tt.soft404s)) {
+				t.Errorf("Stats().Soft404s = %v, want %v", st.Soft404s, len(tt.soft404s))
+			}
+			if requested["/trap"] {
+				t.Error("followed a link of a soft 404")
+			}
+		})
+	}
+}
@@ -0,0 +1,127 @@
+package crawler
+
+import (
+	"encoding/json"
+	"os"
+	"sort"
+	"time"
+)
+
+// stateInterval is how often the crawl state is saved while crawling.
+const stateInterval = 30 * time.Second
+
+// pendingURL is a url that was discovered but not crawled yet.
+type pendingURL struct {
+	URL      string `json:"url"`
+	Depth    int    `json:"depth"`
+	Asset    bool   `json:"asset,omitempty"`
+	External bool   `json:"external,omitempty"`
+	From     string `json:"from,omitempty"`
+}
+
+// crawlState is what StateFile holds.
+type crawlState struct {
+	Visited []string     `json:"visited"`
+	Pending []pendingURL `json:"pending"`
+}
+
+// markDone records that the url raw, claimed as rec.URL, was crawled, and
+// queues what it links to. The links are queued before raw is dropped so a
+// state saved in between never loses them.
+func (c *Crawler) markDone(raw string, rec Record, depth int, urls, assets []string) {
+	for _, u := range urls {
+		c.pending.Store(u, pendingURL{URL: u, Depth: depth + 1})
+	}
+	for _, u := range assets {
+		c.pending.Store(u, pendingURL{URL: u, Asset: true})
+	}
+
+	c.completed.Store(rec.URL, struct{}{})
+	if rec.FinalURL != "" {
+		c.completed.Store(rec.FinalURL, struct{}{})
+	}
+	c.pending.Delete(raw)
+}
+
+// loadState reads StateFile, marks its urls as visited and returns the
+// pending ones to crawl again.
+func (c *Crawler) loadState() ([]pendingURL, error) {
+	data, err := os.ReadFile(c.StateFile)
+	if err != nil {
+		return nil, err
+	}
+
+	var state crawlState
+	if err := json.Unmarshal(data, &state); err != nil {
+		return nil, err
+	}
+
+	for _, u := range state.Visited {
+		c.visited.Store(c.visitedKey(u), struct{}{})
+		c.completed.Store(u, struct{}{})
+	}
+
+	c.Logger.Info("resuming", "visited", len(state.Visited), "pending", len(state.Pending))
+
+	return state.Pending, nil
+}
+
+// saveState writes the visited and pending urls to StateFile. It writes to
+// a temporary file first so a crash never leaves a truncated state behind.
+func (c *Crawler) saveState() error {
+	state := crawlState{Visited: []string{}, Pending: []pendingURL{}}
+
+	c.completed.Range(func(k, _ interface{}) bool {
+		state.Visited = append(state.Visited, k.(string))
+		return true
+	})
+	c.pending.Range(func(_, v interface{}) bool {
+		state.Pending = append(state.Pending, v.(pendingURL))
+		return true
+	})
+
+	sort.Strings(state.Visited)
+	sort.Slice(state.Pending, func(i, j int) bool {
+		return state.Pending[i].URL < state.Pending[j].URL
+	})
+
+	data, err := json.Marshal(state)
+	if err != nil {
+		return err
+	}
+
+	return writeFile(c.StateFile, data)
+}
+
+// persistState saves the state every stateInterval until the returned
+// function is called, which saves it one last time.
+func (c *Crawler) persistState() func() {
+	done := make(chan struct{})
+	stopped := make(chan struct{})
+
+	go func() {
+		defer close(stopped)
+
+		ticker := time.NewTicker(stateInterval)
+		defer ticker.Stop()
+
+		for {
+			select {
+			case <-ticker.C:
+				if err := c.saveState(); err != nil {
+					c.Logger.Error("error saving the crawl state", "err", err)
+				}
+			case <-done:
+				return
+			}
+		}
+	}()
+
+	return func() {
+		close(done)
+		<-stopped
+		if err := c.saveState(); err != nil {
+			c.Logger.Error("error saving the crawl state", "err", err)
+		}
+	}
+}
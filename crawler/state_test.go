@@ -0,0 +1,98 @@
+package crawler
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"reflect"
+	"sort"
+	"sync"
+	"testing"
+)
+
+func TestCrawler_Resume(t *testing.T) {
+	var mutex sync.Mutex
+	hits := []string{}
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		mutex.Lock()
+		hits = append(hits, r.URL.Path)
+		mutex.Unlock()
+
+		switch r.URL.Path {
+		case "/docs":
+			fmt.Fprint(w, `<a href="/docs/a">a</a><a href="/docs/b">b</a>`)
+		case "/docs/a", "/docs/b":
+			fmt.Fprint(w, `<p>page</p>`)
+		default:
+			http.NotFound(w, r)
+		}
+	}))
+	defer server.Close()
+
+	tests := []struct {
+		name     string
+		state    *crawlState
+		wantHits []string
+	}{
+		{
+			name:     "Test fresh crawl",
+			wantHits: []string{"/docs", "/docs/a", "/docs/b"},
+		},
+		{
+			name: "Test resume skips visited pages and crawls pending ones",
+			state: &crawlState{
+				Visited: []string{server.URL + "/docs", server.URL + "/docs/a"},
+				Pending: []pendingURL{{URL: server.URL + "/docs/b", Depth: 1}},
+			},
+			wantHits: []string{"/docs/b"},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			dir := t.TempDir()
+			c := New(server.URL+"/docs", dir)
+			c.IgnoreRobots = true
+			c.StateFile = filepath.Join(dir, ".crawl-state.json")
+			c.Resume = tt.state != nil
+
+			if tt.state != nil {
+				data, err := json.Marshal(tt.state)
+				if err != nil {
+					t.Fatal(err)
+				}
+				if err := os.WriteFile(c.StateFile, data, 0644); err != nil {
+					t.Fatal(err)
+				}
+			}
+
+			mutex.Lock()
+			hits = hits[:0]
+			mutex.Unlock()
+
+			if err := c.Run(context.Background()); err != nil {
+				t.Fatalf("Run() error = %v", err)
+			}
+
+			sort.Strings(hits)
+			if !reflect.DeepEqual(hits, tt.wantHits) {
+				t.Errorf("requests = %v, want %v", hits, tt.wantHits)
+			}
+
+			data, err := os.ReadFile(c.StateFile)
+			if err != nil {
+				t.Fatal(err)
+			}
+			var state crawlState
+			if err := json.Unmarshal(data, &state); err != nil {
+				t.Fatal(err)
+			}
+			if len(state.Pending) != 0 || len(state.Visited) != 3 {
+				t.Errorf("state = %+v, want 3 visited and nothing pending", state)
+			}
+		})
+	}
+}
@@ -0,0 +1,231 @@
+package crawler
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"sort"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// How a crawl ended, as reported by Stats.
+const (
+	StatusCompleted   = "completed"
+	StatusTimeLimit   = "time limit reached"
+	StatusInterrupted = "interrupted"
+	StatusFailed      = "failed"
+)
+
+// Stats summarizes a crawl.
+type Stats struct {
+	Status         string                `json:"status,omitempty"`
+	Pages          int64                 `json:"pages"`
+	Assets         int64                 `json:"assets"`
+	Cached         int64                 `json:"cached"`
+	Bytes          int64                 `json:"bytes"`
+	DedupedBytes   int64                 `json:"deduped_bytes"`
+	Errors         int64                 `json:"errors"`
+	External       int64                 `json:"external,omitempty"`
+	BrokenExternal int64                 `json:"broken_external,omitempty"`
+	Soft404s       int64                 `json:"soft_404s,omitempty"`
+	ErrorsByStatus map[int]int64         `json:"errors_by_status"`
+	Proxies        map[string]ProxyStats `json:"proxies,omitempty"`
+	Breakers       map[string]TripStats  `json:"breakers,omitempty"`
+	Elapsed        time.Duration         `json:"elapsed_ns"`
+	PagesPerSecond float64               `json:"pages_per_second"`
+}
+
+// stats holds the counters behind Stats. They are updated by every worker,
+// so they are atomic.
+type stats struct {
+	pages  atomic.Int64
+	assets atomic.Int64
+	cached atomic.Int64
+	bytes  atomic.Int64
+	errors atomic.Int64
+
+	// deduped counts the bytes not written again thanks to DedupContent
+	deduped atomic.Int64
+
+	// external and broken count the off-site links checked and the ones
+	// that failed
+	external atomic.Int64
+	broken   atomic.Int64
+
+	// soft404 counts the pages skipped by DetectSoft404
+	soft404 atomic.Int64
+
+	// active counts the workers busy with a job
+	active atomic.Int64
+
+	mutex    sync.Mutex
+	byStatus map[int]int64
+	started  time.Time
+	finished time.Time
+	status   string
+}
+
+// count adds the outcome of a page or asset to the counters.
+func (s *stats) count(rec Record) {
+	switch {
+	case rec.External:
+		s.external.Add(1)
+		if rec.Error != "" {
+			s.broken.Add(1)
+		}
+	case rec.Soft404:
+		s.soft404.Add(1)
+	case rec.Error != "":
+		s.errors.Add(1)
+		if rec.StatusCode >= 400 {
+			s.mutex.Lock()
+			if s.byStatus == nil {
+				s.byStatus = map[int]int64{}
+			}
+			s.byStatus[rec.StatusCode]++
+			s.mutex.Unlock()
+		}
+	case rec.Cached:
+		s.cached.Add(1)
+	case rec.StatusCode == 0:
+		// skipped before anything was fetched
+	case rec.Asset:
+		s.assets.Add(1)
+	default:
+		s.pages.Add(1)
+	}
+}
+
+// Stats returns the counters of the crawl. While Run is going the elapsed
+// time is measured up to now.
+func (c *Crawler) Stats() Stats {
+	c.stats.mutex.Lock()
+	defer c.stats.mutex.Unlock()
+
+	st := Stats{
+		Status:         c.stats.status,
+		Pages:          c.stats.pages.Load(),
+		Assets:         c.stats.assets.Load(),
+		Cached:         c.stats.cached.Load(),
+		Bytes:          c.stats.bytes.Load(),
+		DedupedBytes:   c.stats.deduped.Load(),
+		Errors:         c.stats.errors.Load(),
+		External:       c.stats.external.Load(),
+		BrokenExternal: c.stats.broken.Load(),
+		Soft404s:       c.stats.soft404.Load(),
+		ErrorsByStatus: map[int]int64{},
+	}
+	for code, n := range c.stats.byStatus {
+		st.ErrorsByStatus[code] = n
+	}
+	if c.proxies != nil {
+		st.Proxies = c.proxies.stats()
+	}
+	if trips := c.breakers.stats(); len(trips) > 0 {
+		st.Breakers = trips
+	}
+
+	if !c.stats.started.IsZero() {
+		end := c.stats.finished
+		if end.IsZero() {
+			end = time.Now()
+		}
+		st.Elapsed = end.Sub(c.stats.started)
+	}
+	if st.Elapsed > 0 {
+		st.PagesPerSecond = float64(st.Pages) / st.Elapsed.Seconds()
+	}
+
+	return st
+}
+
+// status tells how the crawl that ran with ctx ended.
+func (c *Crawler) status(ctx context.Context) string {
+	c.errorsMutex.Lock()
+	fatal := c.fatal
+	c.errorsMutex.Unlock()
+
+	switch {
+	case fatal != nil:
+		return StatusFailed
+	case errors.Is(ctx.Err(), context.DeadlineExceeded):
+		return StatusTimeLimit
+	case ctx.Err() != nil:
+		return StatusInterrupted
+	default:
+		return StatusCompleted
+	}
+}
+
+// WriteStats writes the crawl statistics to fileName as JSON.
+func (c *Crawler) WriteStats(fileName string) error {
+	data, err := json.MarshalIndent(c.Stats(), "", "  ")
+	if err != nil {
+		return err
+	}
+
+	return os.WriteFile(fileName, data, 0644)
+}
+
+// Print writes a human readable summary of st to w.
+func (st Stats) Print(w io.Writer) {
+	if st.Status != "" {
+		fmt.Fprintf(w, "status:     %v\n", st.Status)
+	}
+	fmt.Fprintf(w, "pages:      %v\n", st.Pages)
+	fmt.Fprintf(w, "assets:     %v\n", st.Assets)
+	fmt.Fprintf(w, "cached:     %v\n", st.Cached)
+	fmt.Fprintf(w, "downloaded: %v bytes\n", st.Bytes)
+	if st.DedupedBytes > 0 {
+		fmt.Fprintf(w, "deduped:    %v bytes\n", st.DedupedBytes)
+	}
+	fmt.Fprintf(w, "errors:     %v\n", st.Errors)
+
+	codes := []int{}
+	for code := range st.ErrorsByStatus {
+		codes = append(codes, code)
+	}
+	sort.Ints(codes)
+	for _, code := range codes {
+		fmt.Fprintf(w, "  %v:       %v\n", code, st.ErrorsByStatus[code])
+	}
+
+	if st.External > 0 {
+		fmt.Fprintf(w, "external:   %v checked, %v broken\n", st.External, st.BrokenExternal)
+	}
+	if st.Soft404s > 0 {
+		fmt.Fprintf(w, "soft 404s:  %v\n", st.Soft404s)
+	}
+
+	proxies := []string{}
+	for p := range st.Proxies {
+		proxies = append(proxies, p)
+	}
+	sort.Strings(proxies)
+	if len(proxies) > 0 {
+		fmt.Fprintf(w, "proxies:\n")
+	}
+	for _, p := range proxies {
+		fmt.Fprintf(w, "  %v: %v ok, %v failed\n", p, st.Proxies[p].OK, st.Proxies[p].Failed)
+	}
+
+	hosts := []string{}
+	for host := range st.Breakers {
+		hosts = append(hosts, host)
+	}
+	sort.Strings(hosts)
+	if len(hosts) > 0 {
+		fmt.Fprintf(w, "tripped:\n")
+	}
+	for _, host := range hosts {
+		fmt.Fprintf(w, "  %v: %v times, %v requests skipped\n", host, st.Breakers[host].Trips, st.Breakers[host].Skipped)
+	}
+
+	fmt.Fprintf(w, "elapsed:    %v\n", st.Elapsed.Round(time.Millisecond))
+	fmt.Fprintf(w, "pages/s:    %.2f\n", st.PagesPerSecond)
+}
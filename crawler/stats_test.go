@@ -0,0 +1,73 @@
+package crawler
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestCrawler_Stats(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/docs":
+			fmt.Fprint(w, `<a href="/docs/a">a</a><a href="/docs/missing">missing</a><a href="/docs/gone">gone</a>`)
+		case "/docs/a":
+			fmt.Fprint(w, `<p>a</p>`)
+		case "/docs/gone":
+			http.Error(w, "gone", http.StatusGone)
+		default:
+			http.NotFound(w, r)
+		}
+	}))
+	defer server.Close()
+
+	tests := []struct {
+		name           string
+		wantPages      int64
+		wantErrors     int64
+		wantByStatus   map[int]int64
+		wantPrintLines []string
+	}{
+		{
+			name:         "Test pages and errors are counted",
+			wantPages:    2,
+			wantErrors:   2,
+			wantByStatus: map[int]int64{404: 1, 410: 1},
+			wantPrintLines: []string{
+				"status:     completed",
+				"pages:      2",
+				"errors:     2",
+				"  404:       1",
+				"  410:       1",
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			c := New(server.URL+"/docs", t.TempDir())
+			c.IgnoreRobots = true
+			c.Run(context.Background())
+
+			st := c.Stats()
+			if st.Pages != tt.wantPages || st.Errors != tt.wantErrors || !reflect.DeepEqual(st.ErrorsByStatus, tt.wantByStatus) {
+				t.Errorf("Stats() = %+v, want %v pages, %v errors by status %v", st, tt.wantPages, tt.wantErrors, tt.wantByStatus)
+			}
+			if st.Bytes == 0 || st.Elapsed <= 0 || st.PagesPerSecond <= 0 {
+				t.Errorf("Stats() = %+v, want bytes, elapsed time and rate", st)
+			}
+
+			var buf bytes.Buffer
+			st.Print(&buf)
+			for _, line := range tt.wantPrintLines {
+				if !strings.Contains(buf.String(), line+"\n") {
+					t.Errorf("Print() = %q, want a line %q", buf.String(), line)
+				}
+			}
+		})
+	}
+}
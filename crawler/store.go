@@ -0,0 +1,130 @@
+package crawler
+
+import (
+	"fmt"
+	"io/fs"
+	"os"
+	"sort"
+	"sync"
+)
+
+// Store is where a Crawler saves the pages and assets it downloads, with
+// their metadata, under the paths its Records report. Files the caller
+// names itself, like StateFile, WARCFile or a report, are always written
+// to disk.
+//
+// Any type with Get and Put can be plugged in as Crawler.Store to save
+// somewhere else, a database or a cache for instance. Keys are file paths
+// joined with filepath.Join, starting with the output directory. Both
+// methods are called from several workers at once. Streaming big assets,
+// ResumePartial and DedupContent only work on the Filesystem, with any
+// other Store those bodies are held in memory and saved with Put.
+type Store interface {
+	// Get returns what was saved as key, reporting false when nothing was.
+	Get(key string) ([]byte, bool)
+
+	// Put saves data as key, replacing what was saved before.
+	Put(key string, data []byte, meta ObjectMeta) error
+}
+
+// ObjectMeta describes what a Store is given to save, for stores that keep
+// it alongside the data.
+type ObjectMeta struct {
+	// URL is the page or asset the data was downloaded from, or the one
+	// its metadata describes.
+	URL string
+
+	// ContentType is the media type of the data.
+	ContentType string
+}
+
+// Filesystem is the Store saving to disk, the default one. Files are
+// replaced atomically, through a temp file.
+type Filesystem struct{}
+
+func (Filesystem) Get(key string) ([]byte, bool) {
+	data, err := os.ReadFile(key)
+
+	return data, err == nil
+}
+
+func (Filesystem) Put(key string, data []byte, _ ObjectMeta) error {
+	return writeFile(key, data)
+}
+
+// Memory is a Store keeping every file in memory, so a crawl never touches
+// the disk. Its zero value is ready to use and it can be read while the
+// crawl runs.
+type Memory struct {
+	mutex sync.Mutex
+	files map[string][]byte
+}
+
+func (m *Memory) Get(key string) ([]byte, bool) {
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+
+	data, ok := m.files[key]
+	if !ok {
+		return nil, false
+	}
+
+	return append([]byte(nil), data...), true
+}
+
+func (m *Memory) Put(key string, data []byte, _ ObjectMeta) error {
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+
+	if m.files == nil {
+		m.files = map[string][]byte{}
+	}
+	m.files[key] = append([]byte(nil), data...)
+
+	return nil
+}
+
+// Files returns the names of every file saved, sorted.
+func (m *Memory) Files() []string {
+	m.mutex.Lock()
+	names := make([]string, 0, len(m.files))
+	for name := range m.files {
+		names = append(names, name)
+	}
+	m.mutex.Unlock()
+
+	sort.Strings(names)
+
+	return names
+}
+
+// Content returns the body saved for rec, read back from the Store.
+func (c *Crawler) Content(rec Record) ([]byte, error) {
+	if rec.Path == "" {
+		return nil, fmt.Errorf("%v was not saved", rec.URL)
+	}
+
+	data, ok := c.store().Get(rec.Path)
+	if !ok {
+		return nil, fmt.Errorf("%v: %w", rec.Path, fs.ErrNotExist)
+	}
+
+	return data, nil
+}
+
+// store returns the Store in use, the Filesystem unless Store is set.
+func (c *Crawler) store() Store {
+	if c.Store == nil {
+		return Filesystem{}
+	}
+
+	return c.Store
+}
+
+// onDisk reports whether files are saved to disk, which streaming big
+// assets, resuming them and DedupContent's hard links need.
+func (c *Crawler) onDisk() bool {
+	_, ok := c.store().(Filesystem)
+
+	return ok
+}
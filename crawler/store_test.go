@@ -0,0 +1,166 @@
+package crawler
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io/fs"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestCrawler_RunMemory(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		// fresh for the second run
+		w.Header().Set("Cache-Control", "max-age=3600")
+		switch r.URL.Path {
+		case "/docs":
+			fmt.Fprint(w, `<p><img src="/docs/logo.png"></p><a href="/docs/intro">intro</a>`)
+		case "/docs/intro":
+			fmt.Fprint(w, `<a href="/docs">back</a>`)
+		case "/docs/logo.png":
+			w.Header().Set("Content-Type", "image/png")
+			fmt.Fprint(w, "png")
+		default:
+			http.NotFound(w, r)
+		}
+	}))
+	defer server.Close()
+
+	files := []string{
+		filepath.Join("docs", "docs.html"),
+		filepath.Join("docs", "docs.meta.json"),
+		filepath.Join("docs", "intro", "intro.html"),
+		filepath.Join("docs", "intro", "intro.meta.json"),
+		filepath.Join("docs", "logo.png"),
+	}
+
+	tests := []struct {
+		name      string
+		mirror    bool
+		runs      int
+		wantFiles []string
+		wantBody  map[string]string
+	}{
+		{
+			name:      "Test pages, assets and metadata are kept in memory and read back",
+			runs:      2,
+			wantFiles: files,
+			wantBody: map[string]string{
+				"/docs":          `href="/docs/intro"`,
+				"/docs/intro":    `href="/docs"`,
+				"/docs/logo.png": "png",
+			},
+		},
+		{
+			name:      "Test mirror links are rewritten in memory",
+			mirror:    true,
+			runs:      1,
+			wantFiles: files,
+			wantBody: map[string]string{
+				"/docs":          `href="intro/intro.html"`,
+				"/docs/intro":    `href="../docs.html"`,
+				"/docs/logo.png": "png",
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			dir := filepath.Join(t.TempDir(), "out")
+			store := &Memory{}
+
+			for run := 0; run < tt.runs; run++ {
+				c := New(server.URL+"/docs", dir)
+				c.IgnoreRobots = true
+				c.Assets = true
+				c.Mirror = tt.mirror
+				c.Store = store
+				if err := c.Run(context.Background()); err != nil {
+					t.Fatalf("Run() error = %v", err)
+				}
+
+				for _, rec := range c.Records() {
+					if cached := run == 1; rec.Cached != cached {
+						t.Errorf("run %v: %v cached = %v, want %v", run, rec.URL, rec.Cached, cached)
+					}
+
+					data, err := c.Content(rec)
+					if err != nil {
+						t.Fatalf("Content(%v) error = %v", rec.URL, err)
+					}
+					if want := tt.wantBody[strings.TrimPrefix(rec.URL, server.URL)]; !strings.Contains(string(data), want) {
+						t.Errorf("Content(%v) = %s, want it to contain %v", rec.URL, data, want)
+					}
+				}
+			}
+
+			got := []string{}
+			for _, name := range store.Files() {
+				rel, err := filepath.Rel(dir, name)
+				if err != nil {
+					t.Fatal(err)
+				}
+				got = append(got, rel)
+			}
+			if !reflect.DeepEqual(got, tt.wantFiles) {
+				t.Errorf("Files() = %v, want %v", got, tt.wantFiles)
+			}
+
+			if _, err := os.Stat(dir); !errors.Is(err, fs.ErrNotExist) {
+				t.Errorf("output dir was created: %v", err)
+			}
+		})
+	}
+}
+
+func TestMemory_Get(t *testing.T) {
+	tests := []struct {
+		name   string
+		put    map[string]string
+		get    string
+		want   string
+		wantOK bool
+	}{
+		{
+			name:   "Test saved file",
+			put:    map[string]string{"a.html": "a"},
+			get:    "a.html",
+			want:   "a",
+			wantOK: true,
+		},
+		{
+			name:   "Test file replaced",
+			put:    map[string]string{"a.html": "b"},
+			get:    "a.html",
+			want:   "b",
+			wantOK: true,
+		},
+		{
+			name: "Test missing file",
+			get:  "a.html",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var m Memory
+			for name, data := range tt.put {
+				if err := m.Put(name, []byte(data), ObjectMeta{}); err != nil {
+					t.Fatalf("Put() error = %v", err)
+				}
+			}
+
+			got, ok := m.Get(tt.get)
+			if ok != tt.wantOK {
+				t.Fatalf("Get() ok = %v, want %v", ok, tt.wantOK)
+			}
+			if string(got) != tt.want {
+				t.Errorf("Get() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,143 @@
+package crawler
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"hash"
+	"io"
+	"os"
+	"path/filepath"
+)
+
+// tempFile is a file being written next to the one it will replace. Until
+// commit renames it into place, a copy saved before is left as it was.
+type tempFile struct {
+	*os.File
+	target string
+
+	// partial files are kept when the download fails, to resume it
+	partial bool
+}
+
+// createTemp opens a temporary file in the directory of fileName, creating
+// the directory if needed.
+func createTemp(fileName string) (*tempFile, error) {
+	dir := filepath.Dir(fileName)
+	if err := os.MkdirAll(dir, os.ModePerm); err != nil {
+		return nil, err
+	}
+
+	f, err := os.CreateTemp(dir, "."+filepath.Base(fileName)+".*.tmp")
+	if err != nil {
+		return nil, err
+	}
+
+	// temp files are private, saved copies are not
+	if err := f.Chmod(0644); err != nil {
+		f.Close()
+		os.Remove(f.Name())
+		return nil, err
+	}
+
+	return &tempFile{File: f, target: fileName}, nil
+}
+
+// commit closes the file and moves it to its final name.
+func (t *tempFile) commit() error {
+	if err := t.Close(); err != nil {
+		os.Remove(t.Name())
+		return err
+	}
+
+	if err := os.Rename(t.Name(), t.target); err != nil {
+		os.Remove(t.Name())
+		return err
+	}
+
+	return nil
+}
+
+// writeFile saves data as fileName through a temp file, so a crash halfway
+// leaves either the previous copy or none, never a truncated one.
+func writeFile(fileName string, data []byte) error {
+	tmp, err := createTemp(fileName)
+	if err != nil {
+		return err
+	}
+
+	if _, err := tmp.Write(data); err != nil {
+		tmp.discard()
+		return err
+	}
+
+	return tmp.commit()
+}
+
+// discard closes and removes the file.
+func (t *tempFile) discard() {
+	t.Close()
+	os.Remove(t.Name())
+}
+
+// abort gives up on a download that failed halfway: the file is discarded,
+// unless it is partial.
+func (t *tempFile) abort() {
+	if t.partial {
+		t.Close()
+		return
+	}
+
+	t.discard()
+}
+
+// stream copies body to fileName, so a big asset is never held in memory.
+// The copy only replaces fileName once it is complete and within MaxSize.
+// With DedupContent it is stored under its hash instead, and fileName links
+// to it.
+func (c *Crawler) stream(ctx context.Context, url, fileName string, body io.Reader, r *response) error {
+	tmp, err := createTemp(fileName)
+	if err != nil {
+		return err
+	}
+
+	return c.streamTo(ctx, url, tmp, sha256.New(), body, r, 0)
+}
+
+// streamTo appends body to tmp, which holds the first start bytes of the
+// resource already, all of them written to h too, and commits it.
+func (c *Crawler) streamTo(ctx context.Context, url string, tmp *tempFile, h hash.Hash, body io.Reader, r *response, start int64) error {
+	fileName := tmp.target
+
+	n, err := io.Copy(io.MultiWriter(tmp, h), body)
+	r.size = start + n
+	c.stats.bytes.Add(n)
+	if err != nil {
+		tmp.abort()
+		return c.timeoutError(ctx, url, err)
+	}
+
+	if c.MaxSize > 0 && r.size > c.MaxSize {
+		tmp.discard()
+		return fmt.Errorf("%w: %v is over the limit of %v bytes", ErrTooLarge, url, c.MaxSize)
+	}
+
+	r.hash = hex.EncodeToString(h.Sum(nil))
+	if !c.DedupContent {
+		return tmp.commit()
+	}
+
+	object, stored, err := c.storeContent(r.hash, r.size, func(object string) error {
+		tmp.target = object
+		return tmp.commit()
+	})
+	if stored {
+		tmp.discard()
+	}
+	if err != nil {
+		return err
+	}
+
+	return linkFile(object, fileName)
+}
@@ -0,0 +1,164 @@
+package crawler
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestCrawler_downloadTo(t *testing.T) {
+	body := strings.Repeat("x", 100)
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		// flushing first hides the length from the client
+		w.(http.Flusher).Flush()
+		fmt.Fprint(w, body)
+	}))
+	defer server.Close()
+
+	tests := []struct {
+		name     string
+		maxSize  int64
+		existing string
+		wantErr  bool
+		want     string
+	}{
+		{
+			name: "Test body is streamed to the file",
+			want: body,
+		},
+		{
+			name:     "Test previous copy is replaced",
+			existing: "old",
+			want:     body,
+		},
+		{
+			name:     "Test oversized body leaves the previous copy",
+			maxSize:  99,
+			existing: "old",
+			wantErr:  true,
+			want:     "old",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			dir := t.TempDir()
+			fileName := filepath.Join(dir, "img", "logo.png")
+			if tt.existing != "" {
+				if err := os.MkdirAll(filepath.Dir(fileName), os.ModePerm); err != nil {
+					t.Fatal(err)
+				}
+				if err := os.WriteFile(fileName, []byte(tt.existing), 0644); err != nil {
+					t.Fatal(err)
+				}
+			}
+
+			c := New(server.URL, dir)
+			c.MaxSize = tt.maxSize
+			client, err := c.newClient()
+			if err != nil {
+				t.Fatal(err)
+			}
+			c.client = client
+
+			resp, err := c.downloadTo(context.Background(), server.URL+"/img/logo.png", fileName)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("downloadTo() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if err == nil && (resp.body != nil || resp.size != int64(len(body))) {
+				t.Errorf("downloadTo() kept %v bytes in memory and wrote %v, want none and %v", len(resp.body), resp.size, len(body))
+			}
+
+			got, err := os.ReadFile(fileName)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if string(got) != tt.want {
+				t.Errorf("saved %q, want %q", got, tt.want)
+			}
+
+			// nothing but the file itself is left behind
+			entries, err := os.ReadDir(filepath.Dir(fileName))
+			if err != nil {
+				t.Fatal(err)
+			}
+			if len(entries) != 1 {
+				t.Errorf("dir has %v entries, want only %v", len(entries), filepath.Base(fileName))
+			}
+		})
+	}
+}
+
+func Test_writeFile(t *testing.T) {
+	tests := []struct {
+		name     string
+		existing string
+		dirInWay bool
+		wantErr  bool
+	}{
+		{
+			name: "Test new file is written",
+		},
+		{
+			name:     "Test previous copy is replaced",
+			existing: "old",
+		},
+		{
+			name:     "Test failed rename leaves no temp file",
+			dirInWay: true,
+			wantErr:  true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			dir := t.TempDir()
+			fileName := filepath.Join(dir, "page.html")
+			if tt.existing != "" {
+				if err := os.WriteFile(fileName, []byte(tt.existing), 0644); err != nil {
+					t.Fatal(err)
+				}
+			}
+			// a non-empty directory can't be replaced by a file
+			if tt.dirInWay {
+				if err := os.MkdirAll(filepath.Join(fileName, "child"), os.ModePerm); err != nil {
+					t.Fatal(err)
+				}
+			}
+
+			err := writeFile(fileName, []byte("new"))
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("writeFile() error = %v, wantErr %v", err, tt.wantErr)
+			}
+
+			entries, err := os.ReadDir(dir)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if len(entries) != 1 {
+				t.Errorf("dir has %v entries, want only page.html", len(entries))
+			}
+			if tt.wantErr {
+				return
+			}
+
+			got, err := os.ReadFile(fileName)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if string(got) != "new" {
+				t.Errorf("saved %q, want %q", got, "new")
+			}
+			info, err := os.Stat(fileName)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if info.Mode().Perm() != 0644 {
+				t.Errorf("saved with mode %v, want %v", info.Mode().Perm(), os.FileMode(0644))
+			}
+		})
+	}
+}
@@ -0,0 +1,142 @@
+package crawler
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+	"sync"
+
+	"golang.org/x/time/rate"
+)
+
+// minBurst is the smallest chunk a throttled body is read in, so low rates
+// don't turn into a syscall per byte.
+const minBurst = 4 * 1024
+
+// rateUnits are the suffixes ParseRate accepts, in bytes.
+var rateUnits = []struct {
+	suffix string
+	size   int64
+}{
+	{"gib", 1 << 30}, {"mib", 1 << 20}, {"kib", 1 << 10},
+	{"gb", 1000 * 1000 * 1000}, {"mb", 1000 * 1000}, {"kb", 1000},
+	{"g", 1 << 30}, {"m", 1 << 20}, {"k", 1 << 10},
+	{"b", 1},
+}
+
+// ParseRate parses a bandwidth like "500KB/s", "2MiB" or "1024" into bytes
+// per second. The "/s" suffix is optional.
+func ParseRate(s string) (int64, error) {
+	v := strings.ToLower(strings.TrimSpace(s))
+	v = strings.TrimSuffix(v, "/s")
+
+	size := int64(1)
+	for _, unit := range rateUnits {
+		if strings.HasSuffix(v, unit.suffix) {
+			v = strings.TrimSuffix(v, unit.suffix)
+			size = unit.size
+			break
+		}
+	}
+
+	n, err := strconv.ParseFloat(strings.TrimSpace(v), 64)
+	if err != nil || n < 0 {
+		return 0, fmt.Errorf("invalid rate %q", s)
+	}
+
+	return int64(n * float64(size)), nil
+}
+
+// newBandwidth returns the limiter shared by every download, or nil when
+// RateLimit is unset.
+func (c *Crawler) newBandwidth() *rate.Limiter {
+	return newLimiter(c.RateLimit)
+}
+
+// newLimiter returns a limiter of bytesPerSec, or nil when it isn't
+// positive.
+func newLimiter(bytesPerSec int64) *rate.Limiter {
+	if bytesPerSec <= 0 {
+		return nil
+	}
+
+	burst := int(bytesPerSec)
+	if burst < minBurst {
+		burst = minBurst
+	}
+
+	return rate.NewLimiter(rate.Limit(bytesPerSec), burst)
+}
+
+// hostBandwidth holds a limiter per host for RateLimitPerHost, created on
+// the first download from it.
+type hostBandwidth struct {
+	mutex    sync.Mutex
+	limiters map[string]*rate.Limiter
+}
+
+// limiter returns the limiter of host at bytesPerSec, or nil when it isn't
+// positive.
+func (b *hostBandwidth) limiter(host string, bytesPerSec int64) *rate.Limiter {
+	if bytesPerSec <= 0 {
+		return nil
+	}
+
+	b.mutex.Lock()
+	defer b.mutex.Unlock()
+
+	if b.limiters == nil {
+		b.limiters = map[string]*rate.Limiter{}
+	}
+	l, ok := b.limiters[host]
+	if !ok {
+		l = newLimiter(bytesPerSec)
+		b.limiters[host] = l
+	}
+
+	return l
+}
+
+// throttles returns the limiters a download from host is read through:
+// the one of its host and the one of the whole crawl, when they are set.
+func (c *Crawler) throttles(host string) []*rate.Limiter {
+	limiters := []*rate.Limiter{}
+	if l := c.hostRates.limiter(host, c.RateLimitPerHost); l != nil {
+		limiters = append(limiters, l)
+	}
+	if c.bandwidth != nil {
+		limiters = append(limiters, c.bandwidth)
+	}
+
+	return limiters
+}
+
+// throttledReader reads from r no faster than every one of limiters
+// allows. Readers share them, so each limit holds for all the downloads
+// it applies to.
+type throttledReader struct {
+	ctx      context.Context
+	r        io.Reader
+	limiters []*rate.Limiter
+}
+
+func (t *throttledReader) Read(p []byte) (int, error) {
+	for _, l := range t.limiters {
+		if len(p) > l.Burst() {
+			p = p[:l.Burst()]
+		}
+	}
+
+	n, err := t.r.Read(p)
+	if n > 0 {
+		for _, l := range t.limiters {
+			if werr := l.WaitN(t.ctx, n); werr != nil {
+				return n, werr
+			}
+		}
+	}
+
+	return n, err
+}
@@ -0,0 +1,125 @@
+package crawler
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+func TestParseRate(t *testing.T) {
+	tests := []struct {
+		name    string
+		rate    string
+		want    int64
+		wantErr bool
+	}{
+		{name: "Test plain bytes", rate: "1024", want: 1024},
+		{name: "Test decimal kilobytes per second", rate: "500KB/s", want: 500000},
+		{name: "Test binary megabytes", rate: "2MiB", want: 2 << 20},
+		{name: "Test short suffix", rate: "1.5k/s", want: 1536},
+		{name: "Test invalid rate", rate: "fast", wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := ParseRate(tt.rate)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("ParseRate() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if got != tt.want {
+				t.Errorf("ParseRate() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestCrawler_downloadRateLimit(t *testing.T) {
+	body := strings.Repeat("x", 2*minBurst)
+	servers := []string{}
+	for i := 0; i < 2; i++ {
+		server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			w.Write([]byte(body))
+		}))
+		defer server.Close()
+		servers = append(servers, server.URL)
+	}
+
+	tests := []struct {
+		name        string
+		rateLimit   int64
+		perHostRate int64
+		hosts       int
+		downloads   int
+		wantMin     time.Duration
+		wantMax     time.Duration
+	}{
+		{
+			// two bursts' worth of bytes, the first of which is free
+			name:      "Test the limit is shared by concurrent downloads",
+			rateLimit: 2 * minBurst,
+			hosts:     1,
+			downloads: 2,
+			wantMin:   time.Second,
+		},
+		{
+			name:        "Test the per-host limit is shared by downloads from a host",
+			perHostRate: 2 * minBurst,
+			hosts:       1,
+			downloads:   2,
+			wantMin:     time.Second,
+		},
+		{
+			name:        "Test hosts aren't throttled against each other",
+			perHostRate: 2 * minBurst,
+			hosts:       2,
+			downloads:   2,
+			wantMax:     500 * time.Millisecond,
+		},
+		{
+			name:        "Test the crawl limit is a ceiling across hosts",
+			rateLimit:   2 * minBurst,
+			perHostRate: 2 * minBurst,
+			hosts:       2,
+			downloads:   2,
+			wantMin:     time.Second,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			c := New(servers[0], t.TempDir())
+			c.RateLimit = tt.rateLimit
+			c.RateLimitPerHost = tt.perHostRate
+			c.bandwidth = c.newBandwidth()
+			client, err := c.newClient()
+			if err != nil {
+				t.Fatal(err)
+			}
+			c.client = client
+
+			start := time.Now()
+			var wg sync.WaitGroup
+			for i := 0; i < tt.downloads; i++ {
+				wg.Add(1)
+				go func(u string) {
+					defer wg.Done()
+					resp, err := c.download(context.Background(), u)
+					if err != nil || len(resp.body) != len(body) {
+						t.Errorf("download() = %v, %v", resp, err)
+					}
+				}(servers[i%tt.hosts])
+			}
+			wg.Wait()
+
+			elapsed := time.Since(start)
+			if elapsed < tt.wantMin*9/10 {
+				t.Errorf("downloads took %v, want at least %v", elapsed, tt.wantMin)
+			}
+			if tt.wantMax > 0 && elapsed > tt.wantMax {
+				t.Errorf("downloads took %v, want at most %v", elapsed, tt.wantMax)
+			}
+		})
+	}
+}
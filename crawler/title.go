@@ -0,0 +1,36 @@
+package crawler
+
+import (
+	"strings"
+
+	"golang.org/x/net/html"
+)
+
+// pageTitle returns the text of the first non-empty <title> of a page,
+// with its whitespace collapsed. Titles inside inline SVG are skipped.
+func pageTitle(doc *html.Node) string {
+	var title string
+
+	var f func(*html.Node)
+	f = func(n *html.Node) {
+		if title != "" {
+			return
+		}
+		if n.Type == html.ElementNode && n.Data == "title" && n.Namespace == "" {
+			var text strings.Builder
+			for child := n.FirstChild; child != nil; child = child.NextSibling {
+				if child.Type == html.TextNode {
+					text.WriteString(child.Data)
+				}
+			}
+			title = strings.Join(strings.Fields(text.String()), " ")
+			return
+		}
+		for child := n.FirstChild; child != nil; child = child.NextSibling {
+			f(child)
+		}
+	}
+	f(doc)
+
+	return title
+}
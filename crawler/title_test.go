@@ -0,0 +1,54 @@
+package crawler
+
+import "testing"
+
+func Test_pageTitle(t *testing.T) {
+	tests := []struct {
+		name string
+		page string
+		want string
+	}{
+		{
+			name: "Test title",
+			page: `<html><head><title>Getting started</title></head><body><p>page</p></body></html>`,
+			want: "Getting started",
+		},
+		{
+			name: "Test whitespace is collapsed",
+			page: "<title>\n  Getting\tstarted \n</title>",
+			want: "Getting started",
+		},
+		{
+			name: "Test missing title",
+			page: `<p>page</p>`,
+			want: "",
+		},
+		{
+			name: "Test first non-empty title is taken",
+			page: `<title> </title><title>Second</title><title>Third</title>`,
+			want: "Second",
+		},
+		{
+			name: "Test svg titles are skipped",
+			page: `<body><svg><title>icon</title></svg><title>Page</title></body>`,
+			want: "Page",
+		},
+		{
+			name: "Test entities are decoded",
+			page: `<title>Q&amp;A</title>`,
+			want: "Q&A",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			doc, err := parseHTML([]byte(tt.page))
+			if err != nil {
+				t.Fatalf("parseHTML() error = %v", err)
+			}
+
+			if got := pageTitle(doc); got != tt.want {
+				t.Errorf("pageTitle() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,74 @@
+package crawler
+
+import (
+	"context"
+	"errors"
+	"net/url"
+	"strings"
+	"sync"
+)
+
+// httpsUpgrades tracks the hosts UpgradeInsecure moved to https, and those
+// of them that turned out not to serve it.
+type httpsUpgrades struct {
+	upgraded sync.Map
+	insecure sync.Map
+}
+
+// upgradeScheme returns the scheme to link a page on host with, given the
+// one of the page linking to it: https rather than http with
+// UpgradeInsecure, unless host fell back to http. Hosts with an explicit
+// port are left alone, https wouldn't be served there.
+func (c *Crawler) upgradeScheme(scheme, host string) string {
+	if !c.UpgradeInsecure {
+		return scheme
+	}
+	if _, ok := c.upgrades.insecure.Load(host); ok {
+		return "http"
+	}
+	if scheme != "http" || (&url.URL{Host: host}).Port() != "" {
+		return scheme
+	}
+
+	c.upgrades.upgraded.Store(host, struct{}{})
+
+	return "https"
+}
+
+// insecureFallback reports whether the failed download of pageURL, err, was
+// an upgraded https request that got no response, marking its host to be
+// fetched over http if so.
+func (c *Crawler) insecureFallback(ctx context.Context, pageURL *url.URL, err error) bool {
+	if err == nil || !c.UpgradeInsecure || pageURL.Scheme != "https" || ctx.Err() != nil {
+		return false
+	}
+	if _, ok := c.upgrades.upgraded.Load(pageURL.Host); !ok {
+		return false
+	}
+
+	var statusErr *StatusError
+	if errors.As(err, &statusErr) || errors.Is(err, ErrTooLarge) || isFatal(err) {
+		return false
+	}
+
+	c.upgrades.insecure.Store(pageURL.Host, struct{}{})
+
+	return true
+}
+
+// fellBack reports whether pageURL was upgraded to https on a host that
+// fell back to http since.
+func (c *Crawler) fellBack(pageURL *url.URL) bool {
+	if !c.UpgradeInsecure || pageURL.Scheme != "https" {
+		return false
+	}
+	_, upgraded := c.upgrades.upgraded.Load(pageURL.Host)
+	_, insecure := c.upgrades.insecure.Load(pageURL.Host)
+
+	return upgraded && insecure
+}
+
+// insecureURL returns the http variant of the https url u.
+func insecureURL(u string) string {
+	return "http" + strings.TrimPrefix(u, "https")
+}
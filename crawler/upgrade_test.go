@@ -0,0 +1,134 @@
+package crawler
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"sort"
+	"sync"
+	"testing"
+)
+
+func TestCrawler_RunUpgradeInsecure(t *testing.T) {
+	tests := []struct {
+		name         string
+		https        bool
+		wantRequests []string
+		wantRecords  []string
+	}{
+		{
+			name:  "Test links are fetched over https",
+			https: true,
+			wantRequests: []string{
+				"http://site.test/docs",
+				"https://site.test/docs/a",
+				"https://site.test/docs/b",
+			},
+			wantRecords: []string{
+				"http://site.test/docs",
+				"https://site.test/docs/a",
+				"https://site.test/docs/b",
+			},
+		},
+		{
+			name: "Test host without https falls back to http",
+			wantRequests: []string{
+				"http://site.test/docs",
+				"http://site.test/docs/a",
+				"http://site.test/docs/b",
+			},
+			wantRecords: []string{
+				"http://site.test/docs",
+				"https://site.test/docs/a -> http://site.test/docs/a",
+				"https://site.test/docs/b -> http://site.test/docs/b",
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var mutex sync.Mutex
+			requests := []string{}
+			site := func(scheme string) http.Handler {
+				return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+					mutex.Lock()
+					if r.URL.Path != "/robots.txt" {
+						requests = append(requests, scheme+"://site.test"+r.URL.Path)
+					}
+					mutex.Unlock()
+
+					switch r.URL.Path {
+					case "/robots.txt":
+						fmt.Fprint(w, "User-agent: *\nDisallow: /docs/secret\n")
+					case "/docs":
+						fmt.Fprint(w, `<a href="http://site.test/docs/a">a</a><a href="/docs/b">b</a><a href="/docs/secret">secret</a><a href="https://site.test/docs/a">again</a>`)
+					default:
+						fmt.Fprint(w, `<p>page</p>`)
+					}
+				})
+			}
+
+			secure := httptest.NewTLSServer(site("https"))
+			defer secure.Close()
+
+			// the proxy resolves site.test, to a server with or without https
+			proxy := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				if r.Method != http.MethodConnect {
+					site("http").ServeHTTP(w, r)
+					return
+				}
+				if !tt.https {
+					http.Error(w, "no https", http.StatusBadGateway)
+					return
+				}
+
+				upstream, err := net.Dial("tcp", secure.Listener.Addr().String())
+				if err != nil {
+					http.Error(w, err.Error(), http.StatusBadGateway)
+					return
+				}
+				defer upstream.Close()
+				w.WriteHeader(http.StatusOK)
+				conn, buf, err := w.(http.Hijacker).Hijack()
+				if err != nil {
+					return
+				}
+				defer conn.Close()
+				go io.Copy(upstream, buf)
+				io.Copy(conn, upstream)
+			}))
+			defer proxy.Close()
+
+			c := New("http://site.test/docs", t.TempDir())
+			c.Sequential = true
+			c.Retries = 0
+			c.Insecure = true
+			c.Proxies = []string{proxy.URL}
+			c.UpgradeInsecure = true
+			if err := c.Run(context.Background()); err != nil {
+				t.Fatalf("Run() error = %v", err)
+			}
+
+			mutex.Lock()
+			sort.Strings(requests)
+			if !reflect.DeepEqual(requests, tt.wantRequests) {
+				t.Errorf("requests = %v, want %v", requests, tt.wantRequests)
+			}
+			mutex.Unlock()
+
+			got := []string{}
+			for _, rec := range c.Records() {
+				if rec.FinalURL != "" {
+					rec.URL += " -> " + rec.FinalURL
+				}
+				got = append(got, rec.URL)
+			}
+			if !reflect.DeepEqual(got, tt.wantRecords) {
+				t.Errorf("Records() = %v, want %v", got, tt.wantRecords)
+			}
+		})
+	}
+}
@@ -0,0 +1,205 @@
+package crawler
+
+import (
+	"bytes"
+	"compress/gzip"
+	"crypto/rand"
+	"crypto/sha1"
+	"encoding/base32"
+	"fmt"
+	"hash"
+	"io"
+	"net/http"
+	"os"
+	"sort"
+	"sync"
+	"time"
+)
+
+// warcWriter appends WARC 1.1 records to a file, each one compressed as a
+// gzip member of its own so tools can seek to any of them.
+type warcWriter struct {
+	mutex sync.Mutex
+	f     *os.File
+}
+
+// openWARC opens fileName for writing records, after what a previous run
+// stored there when appending, and starts it with a warcinfo record.
+func (c *Crawler) openWARC(fileName string, appending bool) (*warcWriter, error) {
+	flags := os.O_CREATE | os.O_WRONLY | os.O_TRUNC
+	if appending {
+		flags = os.O_CREATE | os.O_WRONLY | os.O_APPEND
+	}
+	f, err := os.OpenFile(fileName, flags, 0644)
+	if err != nil {
+		return nil, err
+	}
+
+	w := &warcWriter{f: f}
+	info := fmt.Sprintf("software: %v\r\nformat: WARC File Format 1.1\r\n", c.UserAgent)
+	header := warcHeader("warcinfo", "", "application/warc-fields")
+	if err := w.write(header, bytes.NewReader([]byte(info)), int64(len(info))); err != nil {
+		f.Close()
+		return nil, err
+	}
+
+	return w, nil
+}
+
+func (w *warcWriter) close() error {
+	return w.f.Close()
+}
+
+// write appends a record made of header, with its length set, and the size
+// bytes of block.
+func (w *warcWriter) write(header map[string]string, block io.Reader, size int64) error {
+	header["Content-Length"] = fmt.Sprint(size)
+
+	w.mutex.Lock()
+	defer w.mutex.Unlock()
+
+	gz := gzip.NewWriter(w.f)
+	fmt.Fprint(gz, "WARC/1.1\r\n")
+	for _, k := range warcFieldOrder(header) {
+		fmt.Fprintf(gz, "%v: %v\r\n", k, header[k])
+	}
+	fmt.Fprint(gz, "\r\n")
+	if _, err := io.Copy(gz, block); err != nil {
+		gz.Close()
+		return err
+	}
+	fmt.Fprint(gz, "\r\n\r\n")
+
+	return gz.Close()
+}
+
+// warcFieldOrder lists the fields of header with the record type and id
+// first, as WARC readers expect, and the others sorted.
+func warcFieldOrder(header map[string]string) []string {
+	keys := []string{"WARC-Type", "WARC-Record-ID"}
+	rest := []string{}
+	for k := range header {
+		if k != "WARC-Type" && k != "WARC-Record-ID" {
+			rest = append(rest, k)
+		}
+	}
+	sort.Strings(rest)
+
+	return append(keys, rest...)
+}
+
+// warcHeader returns the fields every record has.
+func warcHeader(recordType, targetURI, contentType string) map[string]string {
+	header := map[string]string{
+		"WARC-Type":      recordType,
+		"WARC-Record-ID": newRecordID(),
+		"WARC-Date":      time.Now().UTC().Format(time.RFC3339),
+		"Content-Type":   contentType,
+	}
+	if targetURI != "" {
+		header["WARC-Target-URI"] = targetURI
+	}
+
+	return header
+}
+
+// newRecordID returns a random uuid urn.
+func newRecordID() string {
+	b := make([]byte, 16)
+	rand.Read(b)
+	b[6] = b[6]&0x0f | 0x40
+	b[8] = b[8]&0x3f | 0x80
+
+	return fmt.Sprintf("<urn:uuid:%x-%x-%x-%x-%x>", b[0:4], b[4:6], b[6:8], b[8:10], b[10:])
+}
+
+// warcCapture spools the body of a response as it came over the wire,
+// before decoding, so it can be archived once the download is over.
+type warcCapture struct {
+	spool  *os.File
+	digest hash.Hash
+}
+
+// captureWARC makes resp's body be copied to a spool file as it is read.
+// It returns nil when there is no WARC output.
+func (c *Crawler) captureWARC(resp *http.Response) (*warcCapture, error) {
+	if c.warc == nil || resp.Request.Method != http.MethodGet {
+		return nil, nil
+	}
+
+	spool, err := os.CreateTemp("", "web-crawler-warc-*")
+	if err != nil {
+		return nil, err
+	}
+	w := &warcCapture{spool: spool, digest: sha1.New()}
+	resp.Body = struct {
+		io.Reader
+		io.Closer
+	}{io.TeeReader(resp.Body, io.MultiWriter(spool, w.digest)), resp.Body}
+
+	return w, nil
+}
+
+// discard removes the spool file.
+func (w *warcCapture) discard() {
+	if w == nil {
+		return
+	}
+	w.spool.Close()
+	os.Remove(w.spool.Name())
+}
+
+// archive writes the request and response records of resp, whose body was
+// read in full. Transfer-Encoding is left out of the response headers, the
+// body being stored without it. Failing to do so stops the crawl, as the
+// archive would be missing pages.
+func (c *Crawler) archive(w *warcCapture, resp *http.Response) {
+	if w == nil {
+		return
+	}
+
+	// decoders may stop before the end of the encoded body
+	if _, err := io.Copy(io.Discard, resp.Body); err != nil {
+		c.Logger.Error("error reading the rest of the body, not archived", "url", resp.Request.URL.String(), "err", err)
+		return
+	}
+
+	if err := c.writeExchange(w, resp); err != nil {
+		c.fail(fmt.Errorf("error writing the WARC file: %w", err))
+	}
+}
+
+// writeExchange writes the response record of resp and the request record
+// that led to it.
+func (c *Crawler) writeExchange(w *warcCapture, resp *http.Response) error {
+	uri := resp.Request.URL.String()
+
+	var req bytes.Buffer
+	fmt.Fprintf(&req, "%v %v HTTP/1.1\r\nHost: %v\r\n", resp.Request.Method, resp.Request.URL.RequestURI(), resp.Request.URL.Host)
+	resp.Request.Header.Write(&req)
+	req.WriteString("\r\n")
+
+	var head bytes.Buffer
+	fmt.Fprintf(&head, "%v %v\r\n", resp.Proto, resp.Status)
+	resp.Header.WriteSubset(&head, map[string]bool{"Transfer-Encoding": true})
+	head.WriteString("\r\n")
+
+	size, err := w.spool.Seek(0, io.SeekCurrent)
+	if err != nil {
+		return err
+	}
+	if _, err := w.spool.Seek(0, io.SeekStart); err != nil {
+		return err
+	}
+
+	response := warcHeader("response", uri, "application/http;msgtype=response")
+	response["WARC-Payload-Digest"] = "sha1:" + base32.StdEncoding.EncodeToString(w.digest.Sum(nil))
+	if err := c.warc.write(response, io.MultiReader(&head, w.spool), int64(head.Len())+size); err != nil {
+		return err
+	}
+
+	request := warcHeader("request", uri, "application/http;msgtype=request")
+	request["WARC-Concurrent-To"] = response["WARC-Record-ID"]
+
+	return c.warc.write(request, &req, int64(req.Len()))
+}
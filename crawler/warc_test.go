@@ -0,0 +1,165 @@
+package crawler
+
+import (
+	"bufio"
+	"bytes"
+	"compress/gzip"
+	"context"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"testing"
+)
+
+// warcRecord is a record read back from a WARC file.
+type warcRecord struct {
+	header map[string]string
+	block  string
+}
+
+// readWARC parses every record of a gzip-compressed WARC file.
+func readWARC(t *testing.T, fileName string) []warcRecord {
+	f, err := os.Open(fileName)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f.Close()
+
+	gz, err := gzip.NewReader(f)
+	if err != nil {
+		t.Fatal(err)
+	}
+	r := bufio.NewReader(gz)
+
+	records := []warcRecord{}
+	for {
+		version, err := r.ReadString('\n')
+		if err == io.EOF {
+			return records
+		}
+		if err != nil || version != "WARC/1.1\r\n" {
+			t.Fatalf("bad record start %q: %v", version, err)
+		}
+
+		rec := warcRecord{header: map[string]string{}}
+		for {
+			line, err := r.ReadString('\n')
+			if err != nil {
+				t.Fatal(err)
+			}
+			if line == "\r\n" {
+				break
+			}
+			k, v, _ := strings.Cut(strings.TrimSuffix(line, "\r\n"), ": ")
+			rec.header[k] = v
+		}
+
+		n, err := strconv.Atoi(rec.header["Content-Length"])
+		if err != nil {
+			t.Fatal(err)
+		}
+		block := make([]byte, n+4)
+		if _, err := io.ReadFull(r, block); err != nil {
+			t.Fatal(err)
+		}
+		if string(block[n:]) != "\r\n\r\n" {
+			t.Fatalf("record not terminated: %q", block[n:])
+		}
+		rec.block = string(block[:n])
+
+		records = append(records, rec)
+	}
+}
+
+func TestCrawler_RunWARC(t *testing.T) {
+	var compressed bytes.Buffer
+	gz := gzip.NewWriter(&compressed)
+	fmt.Fprint(gz, `<p>compressed</p>`)
+	gz.Close()
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/docs":
+			w.Header().Set("X-Served-By", "test")
+			fmt.Fprint(w, `<a href="/docs/gz">gz</a>`)
+		case "/docs/gz":
+			w.Header().Set("Content-Type", "text/html")
+			w.Header().Set("Content-Encoding", "gzip")
+			w.Write(compressed.Bytes())
+		default:
+			http.NotFound(w, r)
+		}
+	}))
+	defer server.Close()
+
+	dir := t.TempDir()
+	warcFile := filepath.Join(t.TempDir(), "out.warc.gz")
+	c := New(server.URL+"/docs", dir)
+	c.IgnoreRobots = true
+	c.WARCFile = warcFile
+	if err := c.Run(context.Background()); err != nil {
+		t.Fatalf("Run() error = %v", err)
+	}
+
+	records := readWARC(t, warcFile)
+	if len(records) != 5 || records[0].header["WARC-Type"] != "warcinfo" {
+		t.Fatalf("WARC has %v records, want a warcinfo and a request and response per page", len(records))
+	}
+
+	responses := map[string]warcRecord{}
+	ids := map[string]bool{}
+	for _, rec := range records[1:] {
+		ids[rec.header["WARC-Record-ID"]] = true
+		switch rec.header["WARC-Type"] {
+		case "response":
+			responses[rec.header["WARC-Target-URI"]] = rec
+		case "request":
+			if !strings.HasPrefix(rec.block, "GET /docs") {
+				t.Errorf("request record starts with %q, want the request line", rec.block)
+			}
+		}
+	}
+	for _, rec := range records[1:] {
+		if rec.header["WARC-Type"] == "request" && !ids[rec.header["WARC-Concurrent-To"]] {
+			t.Errorf("request record points to unknown record %v", rec.header["WARC-Concurrent-To"])
+		}
+	}
+
+	tests := []struct {
+		name string
+		url  string
+		want []string
+	}{
+		{
+			name: "Test response headers and body are archived",
+			url:  server.URL + "/docs",
+			want: []string{"HTTP/1.1 200 OK\r\n", "X-Served-By: test\r\n", "\r\n\r\n<a href=\"/docs/gz\">gz</a>"},
+		},
+		{
+			name: "Test body is archived as sent",
+			url:  server.URL + "/docs/gz",
+			want: []string{"Content-Encoding: gzip\r\n", "\r\n\r\n" + compressed.String()},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			rec, ok := responses[tt.url]
+			if !ok {
+				t.Fatalf("no response record for %v", tt.url)
+			}
+			for _, want := range tt.want {
+				if !strings.Contains(rec.block, want) {
+					t.Errorf("response record %q doesn't contain %q", rec.block, want)
+				}
+			}
+			if rec.header["Content-Type"] != "application/http;msgtype=response" {
+				t.Errorf("response record Content-Type = %v", rec.header["Content-Type"])
+			}
+		})
+	}
+}
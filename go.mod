@@ -1,5 +1,11 @@
 module mdelclaro/web-crawler
 
-go 1.18
+go 1.21
 
-require golang.org/x/net v0.8.0
+require (
+	github.com/andybalholm/brotli v1.0.5
+	golang.org/x/net v0.8.0
+	golang.org/x/text v0.8.0
+	golang.org/x/time v0.3.0
+	gopkg.in/yaml.v3 v3.0.1
+)
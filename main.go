@@ -1,288 +1,447 @@
 package main
 
 import (
-	"flag"
-	"fmt"
-	"io"
-	"log"
+	"context"
+	"errors"
+	"log/slog"
+	"net"
 	"net/http"
-	"net/url"
 	"os"
 	"os/signal"
 	"path"
 	"path/filepath"
 	"regexp"
+	"strconv"
 	"strings"
-	"sync"
 	"syscall"
+	"time"
 
-	"golang.org/x/net/html"
+	"mdelclaro/web-crawler/crawler"
 )
 
-var (
-	target, dir string
-	URLs        = []string{}
-	mutex       = &sync.RWMutex{}
-	wg          sync.WaitGroup
-)
+// shutdownTimeout is how long to wait for in-flight downloads after SIGINT.
+const shutdownTimeout = 10 * time.Second
 
-func main() {
-	flag.StringVar(&target, "url", "", "target URL")
-	flag.StringVar(&dir, "dir", "", "directory where files will be saved")
-	flag.Parse()
+// stringList is a flag.Value collecting every use of a repeatable flag.
+type stringList []string
 
-	if target == "" {
-		log.Fatal("url flag is required")
-	}
+func (l *stringList) String() string {
+	return strings.Join(*l, ",")
+}
+
+func (l *stringList) Set(value string) error {
+	*l = append(*l, value)
+	return nil
+}
 
-	if !strings.HasPrefix(target, "http") {
-		log.Fatal("invalid url provided. valid ex.: https://github.com")
+func main() {
+	cfg := defaultConfig()
+	cfg.flagSet().Parse(os.Args[1:])
+
+	// the file is loaded first so the flags override it
+	if cfg.File != "" {
+		file := defaultConfig()
+		if err := file.load(cfg.File); err != nil {
+			fatal("error reading the config", "file", cfg.File, "err", err)
+		}
+		cfg = file
+		cfg.flagSet().Parse(os.Args[1:])
 	}
 
-	if dir == "" {
-		dir = "./data"
-		println("dir flag is empty. using default ./data")
+	level := slog.LevelInfo
+	if cfg.Verbose {
+		level = slog.LevelDebug
+	} else if cfg.Quiet || cfg.OnlyErrors {
+		level = slog.LevelError
 	}
+	logger := slog.New(slog.NewTextHandler(os.Stderr, &slog.HandlerOptions{Level: level}))
+	slog.SetDefault(logger)
 
-	// listen to kill commands
-	c := make(chan os.Signal)
-	signal.Notify(c, os.Interrupt, syscall.SIGINT)
-	go func() {
-		<-c
-		println("\nstopping...")
-		os.Exit(1)
-	}()
+	targets := splitList(cfg.Target)
+	if cfg.Seeds != "" {
+		seeds, err := readLines(cfg.Seeds)
+		if err != nil {
+			fatal("error reading the seeds", "err", err)
+		}
+		targets = append(targets, seeds...)
+	}
 
-	err := process(target)
-	if err != nil {
-		panic(err)
+	if len(targets) == 0 {
+		fatal("url flag is required")
 	}
 
-	wg.Wait()
+	if cfg.Strategy != crawler.StrategyBFS && cfg.Strategy != crawler.StrategyDFS {
+		fatal("invalid strategy, expected bfs or dfs", "strategy", cfg.Strategy)
+	}
+	if cfg.Layout != crawler.LayoutMirror && cfg.Layout != crawler.LayoutFlat && cfg.Layout != crawler.LayoutHostname {
+		fatal("invalid layout, expected mirror, flat or hostname", "layout", cfg.Layout)
+	}
+	if cfg.Ext != crawler.ExtHTML && cfg.Ext != crawler.ExtAuto && cfg.Ext != crawler.ExtPreserve {
+		fatal("invalid ext, expected html, auto or preserve", "ext", cfg.Ext)
+	}
+	if cfg.Scope != crawler.ScopePath && cfg.Scope != crawler.ScopeHost && cfg.Scope != crawler.ScopeDomain {
+		fatal("invalid scope, expected path, host or domain", "scope", cfg.Scope)
+	}
 
-	println("done!")
-}
+	for _, t := range targets {
+		if !strings.HasPrefix(t, "http") {
+			fatal("invalid url provided. valid ex.: https://github.com", "url", t)
+		}
+	}
 
-func process(target string) error {
-	// remove "/" suffix to avoid duplicating it
-	target = strings.TrimSuffix(target, "/")
-	parsedURL, err := url.Parse(target)
-	if err != nil {
-		fmt.Printf("error parsing the target: %v", err)
+	if cfg.Dir == "" {
+		cfg.Dir = "./data"
+		logger.Info("dir flag is empty, using default ./data")
 	}
 
-	// parsing the target
-	target = fmt.Sprintf("%v://%v%v", parsedURL.Scheme, parsedURL.Host, parsedURL.Path)
+	if cfg.StateFile == "" {
+		cfg.StateFile = filepath.Join(cfg.Dir, ".crawl-state.json")
+	}
 
-	ok := false
+	cr := crawler.New(targets[0], cfg.Dir)
+	if cfg.S3 != "" {
+		bucket, prefix, _ := strings.Cut(strings.TrimPrefix(cfg.S3, "s3://"), "/")
+		region := os.Getenv("AWS_REGION")
+		if region == "" {
+			region = "us-east-1"
+		}
+		cr.Store = &crawler.S3{
+			Endpoint:     cfg.S3Endpoint,
+			Region:       region,
+			Bucket:       bucket,
+			Prefix:       prefix,
+			AccessKey:    os.Getenv("AWS_ACCESS_KEY_ID"),
+			SecretKey:    os.Getenv("AWS_SECRET_ACCESS_KEY"),
+			SessionToken: os.Getenv("AWS_SESSION_TOKEN"),
+		}
+	}
+	cr.FromSitemap = cfg.FromSitemap
+	cr.Seeds = targets[1:]
+	cr.AllowedDomains = splitList(cfg.AllowedDomains)
+	cr.MaxDepth = cfg.Depth
+	cr.MaxIdleConnsPerHost = cfg.MaxIdleConns
+	cr.MaxConnsPerHost = cfg.MaxConnsPerHost
+	cr.IdleConnTimeout = cfg.IdleTimeout
+	cr.TLSHandshakeTimeout = cfg.TLSTimeout
+	cr.HTTP2 = cfg.HTTP2
+	cr.Timeout = cfg.Timeout
+	cr.MaxSize = cfg.MaxSize
+	if cfg.RateLimit != "" {
+		rate, err := crawler.ParseRate(cfg.RateLimit)
+		if err != nil {
+			fatal("invalid -rate-limit", "err", err)
+		}
+		cr.RateLimit = rate
+	}
+	if cfg.PerHostRate != "" {
+		rate, err := crawler.ParseRate(cfg.PerHostRate)
+		if err != nil {
+			fatal("invalid -per-host-rate", "err", err)
+		}
+		cr.RateLimitPerHost = rate
+	}
+	cr.Concurrency = cfg.Concurrency
+	cr.Sequential = cfg.Sequential
+	cr.MaxFileDescriptors = cfg.MaxFDs
+	if cfg.MaxFDs == 0 {
+		cr.MaxFileDescriptors, _ = crawler.FileLimit()
+	}
+	cr.Strategy = cfg.Strategy
+	cr.Scope = cfg.Scope
+	cr.Layout = cfg.Layout
+	cr.Ext = cfg.Ext
+	cr.IgnoreRobots = cfg.IgnoreRobots
+	cr.IgnoreMetaRobots = cfg.IgnoreMetaRobots
+	cr.RobotsTTL = cfg.RobotsTTL
+	cr.StrictRobots = cfg.StrictRobots
+	cr.Delay = cfg.Delay
+	cr.Jitter = cfg.Jitter
+	cr.MaxPages = cfg.MaxPages
+	cr.MaxDuration = cfg.MaxDuration
+	cr.UserAgent = cfg.UserAgent
+	cr.MaxRedirects = cfg.MaxRedirects
+	cr.MaxPathFetches = cfg.MaxPathFetches
+	cr.MaxLinksPerPage = cfg.MaxLinksPerPage
+	cr.CheckExternal = cfg.CheckExternal
+	cr.Retries = cfg.Retries
+	cr.MaxHostFailures = cfg.MaxHostFailures
+	cr.HostCooldown = cfg.HostCooldown
+	for _, s := range splitList(cfg.RetryStatus) {
+		code, err := strconv.Atoi(s)
+		if err != nil || code < 100 || code > 599 {
+			fatal("invalid -retry-status, expected http statuses like 429,503", "status", s)
+		}
+		cr.RetryStatuses = append(cr.RetryStatuses, code)
+	}
+	cr.IncludeSubdomains = cfg.IncludeSubdomains
+	cr.FollowPagination = cfg.FollowPagination
+	cr.Assets = cfg.Assets
+	cr.AssetsCrossOrigin = cfg.AssetsCrossOrigin
+	cr.Mirror = cfg.Mirror
+	cr.StripFragments = cfg.StripFragments
+	cr.BasePath = cfg.BasePath
+	cr.Include = compilePatterns(cfg.Include)
+	cr.Exclude = compilePatterns(cfg.Exclude)
+	if cfg.ExcludeFile != "" {
+		patterns, err := readLines(cfg.ExcludeFile)
+		if err != nil {
+			fatal("error reading the excluded paths", "err", err)
+		}
+		for _, p := range patterns {
+			if _, err := path.Match(p, ""); err != nil {
+				fatal("invalid path pattern", "pattern", p, "err", err)
+			}
+		}
+		cr.ExcludePaths = patterns
+	}
+	cr.Accept = cfg.Accept
+	cr.KeepQuery = cfg.KeepQuery
+	cr.IndexName = cfg.IndexName
+	cr.LowercasePaths = cfg.LowercasePaths
+	cr.CaseInsensitivePaths = cfg.CaseInsensitive
+	cr.StateFile = cfg.StateFile
+	cr.WARCFile = cfg.WARC
+	cr.DedupContent = cfg.DedupContent
+	cr.SaveRawHeaders = cfg.SaveRawHeaders
+	cr.Resume = cfg.Resume
+	if cfg.ResumeFrom != "" {
+		records, err := crawler.ReadReport(cfg.ResumeFrom)
+		if err != nil {
+			fatal("invalid -resume-from", "err", err)
+		}
+		cr.ResumeRecords = records
+	}
+	cr.ResumePartial = cfg.ResumePartial
+	cr.Refresh = cfg.Refresh
+	cr.NoTranscode = cfg.NoTranscode
+	cr.DryRun = cfg.DryRun
+	cr.HeadFirst = cfg.HeadFirst
+	cr.DetectSoft404 = cfg.DetectSoft404
+	cr.HonorCanonical = cfg.HonorCanonical
+	if cfg.Progress {
+		cr.Progress = os.Stderr
+	}
+	if cfg.Render != "" {
+		cr.Renderer = crawler.CommandRenderer{Command: strings.Fields(cfg.Render)}
+	}
+	cr.Header = parseHeaders(cfg.Headers)
+	cr.Cookies = parseCookies(cfg.Cookies)
+	cr.CookieFile = cfg.CookieFile
+	cr.Proxy = cfg.Proxy
+	if cfg.Proxies != "" {
+		proxies, err := readLines(cfg.Proxies)
+		if err != nil {
+			fatal("error reading the proxies", "err", err)
+		}
+		cr.Proxies = proxies
+	}
+	cr.Insecure = cfg.Insecure
+	cr.UpgradeInsecure = cfg.UpgradeInsecure
+	if cfg.BasicAuth != "" {
+		user, pass, ok := strings.Cut(cfg.BasicAuth, ":")
+		if !ok {
+			fatal("invalid -basic-auth, expected user:pass")
+		}
+		cr.Username, cr.Password = user, pass
+	}
+	cr.Graph = cfg.Graph != ""
+	cr.Referrers = cfg.BrokenLinks != ""
+	cr.Logger = logger
+
+	// read before -report may replace it
+	var previous []crawler.Record
+	if cfg.Diff != "" {
+		var err error
+		previous, err = crawler.ReadReport(cfg.Diff)
+		if errors.Is(err, os.ErrNotExist) {
+			logger.Info("no previous report, every url is new", "diff", cfg.Diff)
+		} else if err != nil {
+			fatal("invalid -diff", "err", err)
+		}
+	}
 
-	for _, u := range URLs {
-		if target == u {
-			ok = true
+	if cfg.MetricsAddr != "" {
+		ln, err := net.Listen("tcp", cfg.MetricsAddr)
+		if err != nil {
+			fatal("invalid -metrics-addr", "err", err)
 		}
+		mux := http.NewServeMux()
+		mux.Handle("/metrics", cr.MetricsHandler())
+		go func() {
+			if err := http.Serve(ln, mux); err != nil {
+				logger.Error("error serving metrics", "err", err)
+			}
+		}()
+		logger.Info("serving metrics", "addr", ln.Addr().String())
 	}
 
-	if !ok {
-		mutex.Lock()
-		URLs = append(URLs, target)
-		mutex.Unlock()
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	done := make(chan error, 1)
+	go func() {
+		done <- cr.Run(ctx)
+	}()
 
-		var content []byte
-		fp := filepath.Join(dir, parsedURL.Path)
-		fileName := path.Base(parsedURL.Path)
+	// listen to kill commands
+	c := make(chan os.Signal, 1)
+	signal.Notify(c, os.Interrupt, syscall.SIGINT)
 
-		// call it index in case it's the target
-		if fileName == "." {
-			fileName = "index"
+	var err error
+	select {
+	case err = <-done:
+	case <-c:
+		logger.Info("stopping")
+		cancel()
+
+		// give in-flight downloads a chance to stop cleanly
+		select {
+		case err = <-done:
+		case <-time.After(shutdownTimeout):
+			logger.Error("timed out waiting for downloads to stop")
 		}
+	}
 
-		// check for file existence
-		savedContent := checkForFile(fp, fileName+".html")
-		if savedContent == nil {
-			// download page
-			content, err = download(target)
-			if err != nil {
-				fmt.Printf("error downloading the target: %v", err)
-			}
+	if cfg.Report != "" {
+		if err := cr.WriteReport(cfg.Report); err != nil {
+			logger.Error("error writing the report", "err", err)
+		}
+	}
 
-			// save page
-			if err := save(fp, fileName+".html", content); err != nil {
-				fmt.Printf("error saving the target: %v", err)
+	if !cfg.Quiet && !cfg.OnlyErrors {
+		cr.Stats().Print(os.Stderr)
+	}
+
+	if cfg.Diff != "" {
+		diff := cr.Diff(previous)
+		if !cfg.Quiet && !cfg.OnlyErrors {
+			diff.Print(os.Stderr)
+		}
+		if cfg.DiffJSON != "" {
+			if err := crawler.WriteDiff(cfg.DiffJSON, diff); err != nil {
+				logger.Error("error writing the diff", "err", err)
 			}
-		} else {
-			content = savedContent
 		}
+	}
 
-		// parse page content
-		htmlContent, err := parseHTML(content)
-		if err != nil {
-			fmt.Printf("error parsing html content: %v", err)
+	if cfg.StatsJSON != "" {
+		if err := cr.WriteStats(cfg.StatsJSON); err != nil {
+			logger.Error("error writing the statistics", "err", err)
 		}
+	}
 
-		// extract urls from page
-		urls, err := extractUrls(htmlContent, parsedURL)
-		if err != nil {
-			fmt.Printf("error extracting urls: %v", err)
+	if cfg.Sitemap != "" {
+		if err := cr.WriteSitemap(cfg.Sitemap); err != nil {
+			logger.Error("error writing the sitemap", "err", err)
 		}
+	}
 
-		// call process() for each found url recursively
-		for _, u := range urls {
-			wg.Add(1)
-
-			go func(targetUrl string) {
-				defer wg.Done()
-				process(targetUrl)
-			}(u)
+	if cfg.Graph != "" {
+		if err := cr.WriteGraph(cfg.Graph); err != nil {
+			logger.Error("error writing the graph", "err", err)
 		}
 	}
 
-	return nil
-}
+	if cfg.BrokenLinks != "" {
+		if err := cr.WriteBrokenLinks(cfg.BrokenLinks); err != nil {
+			logger.Error("error writing the broken links", "err", err)
+		}
+	}
 
-func download(url string) ([]byte, error) {
-	println("downloading", url)
+	var pageErrs crawler.PageErrors
+	if errors.As(err, &pageErrs) {
+		for _, pageErr := range pageErrs {
+			logger.Error("failed", "err", pageErr)
+		}
+	} else if err != nil {
+		fatal("crawl stopped", "err", err)
+	}
 
-	resp, err := http.Get(url)
-	if err != nil {
-		return nil, err
+	// a non-2xx answer fails its page too, so this gates CI on both
+	if cfg.OnlyErrors && len(pageErrs) > 0 {
+		os.Exit(1)
 	}
 
-	defer resp.Body.Close()
+	logger.Info("done")
+}
 
-	if resp.StatusCode != http.StatusOK {
-		return nil, fmt.Errorf("invalid status code")
-	}
+// fatal logs msg at error level and exits.
+func fatal(msg string, args ...any) {
+	slog.Error(msg, args...)
+	os.Exit(1)
+}
 
-	data, err := io.ReadAll(resp.Body)
-	if err != nil {
-		return nil, err
+// compilePatterns compiles the -include/-exclude values, exiting on the first
+// invalid one.
+func compilePatterns(patterns []string) []*regexp.Regexp {
+	compiled := []*regexp.Regexp{}
+	for _, p := range patterns {
+		r, err := regexp.Compile(p)
+		if err != nil {
+			fatal("invalid pattern", "pattern", p, "err", err)
+		}
+		compiled = append(compiled, r)
 	}
 
-	return data, nil
+	return compiled
 }
 
-func checkForFile(filePath string, fileName string) []byte {
-	data, err := os.ReadFile(filePath + "/" + fileName)
-	if err != nil {
-		println(filePath, "does not exist. downloading and saving...")
-		return nil
+// parseHeaders parses the -header values, exiting on the first malformed
+// one. The values are never logged since they often hold tokens.
+func parseHeaders(headers []string) http.Header {
+	h := http.Header{}
+	for _, header := range headers {
+		k, v, ok := strings.Cut(header, ":")
+		if !ok || strings.TrimSpace(k) == "" {
+			fatal("invalid header, expected \"Key: Value\"")
+		}
+		h.Add(strings.TrimSpace(k), strings.TrimSpace(v))
 	}
 
-	println(filePath, "already exists")
-
-	return data
+	return h
 }
 
-func save(filePath string, fileName string, data []byte) error {
-	if err := os.MkdirAll(filePath, os.ModePerm); err != nil {
-		return err
+// parseCookies parses the -cookie values, exiting on the first malformed
+// one.
+func parseCookies(cookies []string) []*http.Cookie {
+	parsed := []*http.Cookie{}
+	for _, cookie := range cookies {
+		name, value, ok := strings.Cut(cookie, "=")
+		if !ok || strings.TrimSpace(name) == "" {
+			fatal("invalid cookie, expected name=value")
+		}
+		parsed = append(parsed, &http.Cookie{Name: strings.TrimSpace(name), Value: strings.TrimSpace(value)})
 	}
 
-	file, err := os.Create(filePath + "/" + fileName)
-	if err != nil {
-		return err
-	}
-	defer file.Close()
+	return parsed
+}
 
-	_, err = file.Write(data)
-	if err != nil {
-		return err
+// splitList splits a comma-separated flag value, dropping empty entries.
+func splitList(value string) []string {
+	list := []string{}
+	for _, v := range strings.Split(value, ",") {
+		if v = strings.TrimSpace(v); v != "" {
+			list = append(list, v)
+		}
 	}
 
-	return nil
+	return list
 }
 
-func parseHTML(data []byte) (*html.Node, error) {
-	htmlDoc, err := html.Parse(strings.NewReader(string(data)))
+// readLines reads a file of one value per line, like -seeds and -proxies,
+// skipping blank lines and # comments.
+func readLines(fileName string) ([]string, error) {
+	data, err := os.ReadFile(fileName)
 	if err != nil {
 		return nil, err
 	}
 
-	return htmlDoc, nil
-}
-
-func extractUrls(htlmDoc *html.Node, parsedURL *url.URL) ([]string, error) {
-	println("extracting urls from ", parsedURL.Host+parsedURL.Path)
-
-	invalidValues := []string{"#", "/"}
-	urls := []string{}
-
-	targetScheme := parsedURL.Scheme
-	targetURL := parsedURL.Host + parsedURL.Path
-	domain := parsedURL.Host
-
-	// recursively search for <a> tags on html page
-	var f func(*html.Node)
-	f = func(n *html.Node) {
-		if n.Type == html.ElementNode && n.Data == "a" {
-			for _, a := range n.Attr {
-				if a.Key == "href" {
-					newUrl := a.Val
-
-					// check for invalid url values
-					if strings.HasPrefix(newUrl, "#") {
-						continue
-					}
-
-					for _, invalidValue := range invalidValues {
-						if newUrl == invalidValue {
-							continue
-						}
-					}
-
-					// check for same domain
-					if strings.HasPrefix(newUrl, "http") {
-						parsedNewURL, err := url.Parse(newUrl)
-						if err != nil {
-							break
-						}
-
-						if domain != parsedNewURL.Host {
-							continue
-						}
-
-						newUrl = parsedNewURL.Path
-					}
-
-					// check relative path and remove query params
-					if strings.HasPrefix(newUrl, "/") {
-						newUrl = domain + newUrl
-						parsedNewURL, err := url.Parse(newUrl)
-						if err != nil {
-							break
-						}
-						newUrl = parsedNewURL.Path
-					}
-
-					// check if new url is children of target
-					if checkIfChildren(newUrl, targetURL) {
-						// avoid duplicates
-						for _, u := range urls {
-							if u == newUrl {
-								continue
-							}
-						}
-
-						// remove / suffix to check if it's not equal target
-						newUrl = strings.TrimSuffix(newUrl, "/")
-						if newUrl != targetURL {
-							urls = append(urls, fmt.Sprintf("%v://%v", targetScheme, newUrl))
-						}
-					}
-				}
-			}
-		}
-		for c := n.FirstChild; c != nil; c = c.NextSibling {
-			f(c)
+	seeds := []string{}
+	for _, line := range strings.Split(string(data), "\n") {
+		if line = strings.TrimSpace(line); line != "" && !strings.HasPrefix(line, "#") {
+			seeds = append(seeds, line)
 		}
 	}
-	f(htlmDoc)
-
-	return urls, nil
-}
 
-func checkIfChildren(input string, target string) bool {
-	escapedString := regexp.QuoteMeta(target)
-	r := regexp.MustCompile(fmt.Sprintf(`^%v(?:\/.*|)$`, escapedString))
-	return r.MatchString(input)
+	return seeds, nil
 }